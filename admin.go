@@ -0,0 +1,92 @@
+package glog
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+)
+
+type levelResponse struct {
+	Level     string     `json:"level"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type levelRequest struct {
+	Level string `json:"level"`
+	TTL   string `json:"ttl"`
+}
+
+type levelError struct {
+	Error string `json:"error"`
+}
+
+// NewLevelHandler returns http handler to view and change the level held by controller.
+// GET responds with the current level as JSON, PUT and POST change it with a JSON body like
+// {"level": "debug", "ttl": "10m"} or with level and ttl form values. If ttl is set,
+// the previous level is restored after it expires.
+func NewLevelHandler(controller *LevelController) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPut, http.MethodPost:
+			level, ttl, err := parseLevelRequest(r)
+			if err != nil {
+				writeLevelJSON(w, http.StatusBadRequest, levelError{Error: err.Error()})
+				return
+			}
+			if ttl > 0 {
+				controller.SetLevelFor(level, ttl)
+			} else {
+				controller.SetLevel(level)
+			}
+		default:
+			w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+			writeLevelJSON(w, http.StatusMethodNotAllowed, levelError{Error: "method not allowed"})
+			return
+		}
+
+		resp := levelResponse{Level: controller.Level().String()}
+		if expireAt := controller.Expiration(); !expireAt.IsZero() {
+			resp.ExpiresAt = &expireAt
+		}
+		writeLevelJSON(w, http.StatusOK, resp)
+	})
+}
+
+func parseLevelRequest(r *http.Request) (Level, time.Duration, error) {
+	var req levelRequest
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return 0, 0, fmt.Errorf("invalid request body: %w", err)
+		}
+	} else {
+		req.Level = r.FormValue("level")
+		req.TTL = r.FormValue("ttl")
+	}
+
+	var level Level
+	if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+		return 0, 0, fmt.Errorf("invalid level '%s'", req.Level)
+	}
+
+	var ttl time.Duration
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d < 0 {
+			return 0, 0, fmt.Errorf("invalid ttl '%s'", req.TTL)
+		}
+		ttl = d
+	}
+
+	return level, ttl, nil
+}
+
+func writeLevelJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,111 @@
+package glog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func doLevelRequest(t *testing.T, handler http.Handler, method, contentType, body string) (int, map[string]any) {
+	req := httptest.NewRequest(method, "http://testing/log/level", strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	var resp map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json response %q: %s", w.Body.String(), err.Error())
+	}
+	return w.Code, resp
+}
+
+func TestLevelHandler(t *testing.T) {
+	controller := NewLevelController(LevelInfo)
+	handler := NewLevelHandler(controller)
+
+	code, resp := doLevelRequest(t, handler, http.MethodGet, "", "")
+	if code != http.StatusOK || resp["level"] != "INFO" {
+		t.Errorf("unexpected response %d %v", code, resp)
+	}
+
+	code, resp = doLevelRequest(t, handler, http.MethodPut, "application/json", `{"level":"debug"}`)
+	if code != http.StatusOK || resp["level"] != "DEBUG" || controller.Level() != LevelDebug {
+		t.Errorf("unexpected response %d %v", code, resp)
+	}
+	if _, ok := resp["expires_at"]; ok {
+		t.Error("expected no expiration")
+	}
+
+	code, resp = doLevelRequest(t, handler, http.MethodPost, "application/x-www-form-urlencoded", "level=warn")
+	if code != http.StatusOK || resp["level"] != "WARN" || controller.Level() != LevelWarn {
+		t.Errorf("unexpected response %d %v", code, resp)
+	}
+
+	testCases := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		code        int
+	}{
+		{"invalid level", http.MethodPut, "application/json", `{"level":"verbose"}`, http.StatusBadRequest},
+		{"invalid ttl", http.MethodPut, "application/json", `{"level":"debug","ttl":"soon"}`, http.StatusBadRequest},
+		{"negative ttl", http.MethodPost, "application/x-www-form-urlencoded", "level=debug&ttl=-1s", http.StatusBadRequest},
+		{"invalid body", http.MethodPut, "application/json", `{"level":`, http.StatusBadRequest},
+		{"method not allowed", http.MethodDelete, "", "", http.StatusMethodNotAllowed},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			code, resp := doLevelRequest(t, handler, testCase.method, testCase.contentType, testCase.body)
+			if code != testCase.code {
+				t.Errorf("expected %d status, got %d", testCase.code, code)
+			}
+			if resp["error"] == "" {
+				t.Error("expected error message")
+			}
+			if controller.Level() != LevelWarn {
+				t.Errorf("expected level to stay WARN, got %s", controller.Level())
+			}
+		})
+	}
+}
+
+func TestLevelHandlerTTL(t *testing.T) {
+	controller := NewLevelController(LevelInfo)
+	handler := NewLevelHandler(controller)
+
+	code, resp := doLevelRequest(t, handler, http.MethodPut, "application/json", `{"level":"debug","ttl":"50ms"}`)
+	if code != http.StatusOK || resp["level"] != "DEBUG" {
+		t.Errorf("unexpected response %d %v", code, resp)
+	}
+	if _, ok := resp["expires_at"]; !ok {
+		t.Error("expected expiration")
+	}
+
+	// a second temporary change keeps the level to revert to
+	controller.SetLevelFor(LevelError, 50*time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for controller.Level() != LevelInfo && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if controller.Level() != LevelInfo {
+		t.Errorf("expected level to be reverted to INFO, got %s", controller.Level())
+	}
+	if !controller.Expiration().IsZero() {
+		t.Error("expected no expiration after revert")
+	}
+
+	// an explicit change cancels the revert
+	controller.SetLevelFor(LevelDebug, 20*time.Millisecond)
+	controller.SetLevel(LevelWarn)
+	time.Sleep(50 * time.Millisecond)
+	if controller.Level() != LevelWarn {
+		t.Errorf("expected level to stay WARN, got %s", controller.Level())
+	}
+}
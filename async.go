@@ -0,0 +1,256 @@
+package glog
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+const defaultAsyncQueueSize = 1024
+
+// OverflowPolicy defines what AsyncHandler does with a record when the queue is full
+type OverflowPolicy uint8
+
+const (
+	// OverflowBlock waits until the queue has free space
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the record being logged
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest queued record to make space
+	OverflowDropOldest
+	// OverflowDropBelowLevel drops the record if its level is below AsyncOptions.DropLevel and waits otherwise
+	OverflowDropBelowLevel
+)
+
+// AsyncOptions configures AsyncHandler
+type AsyncOptions struct {
+	// QueueSize is the maximum number of queued records, the default is 1024
+	QueueSize int
+	// Overflow is the policy applied when the queue is full
+	Overflow OverflowPolicy
+	// DropLevel is the level below which records are dropped by OverflowDropBelowLevel
+	DropLevel Level
+}
+
+// AsyncHandler passes records to the next handler in a background goroutine, so slow outputs don't add
+// latency to the caller. Records are queued in a bounded ring queue, the overflow policy decides what
+// happens when it is full. Handlers derived by WithAttrs and WithGroup share the queue.
+type AsyncHandler struct {
+	next Handler
+	q    *asyncQueue
+}
+
+type asyncEntry struct {
+	ctx context.Context
+	h   Handler
+	r   Record
+}
+
+type asyncQueue struct {
+	opts AsyncOptions
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	entries  []asyncEntry
+	head     int
+	count    int
+	busy     bool
+	closed   bool
+	// abandoned drops the queued records when Close stopped waiting for them
+	abandoned bool
+	drained   chan struct{}
+
+	dropped atomic.Uint64
+	done    chan struct{}
+}
+
+// NewAsyncHandler creates asynchronous handler passing records to next, call Close to drain the queue
+// and stop the background goroutine
+func NewAsyncHandler(next Handler, opts AsyncOptions) *AsyncHandler {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultAsyncQueueSize
+	}
+
+	q := &asyncQueue{
+		opts:    opts,
+		entries: make([]asyncEntry, opts.QueueSize),
+		done:    make(chan struct{}),
+	}
+	q.notEmpty = sync.NewCond(&q.mu)
+	q.notFull = sync.NewCond(&q.mu)
+	go q.run()
+
+	return &AsyncHandler{next: next, q: q}
+}
+
+func (h *AsyncHandler) Enabled(ctx context.Context, level Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle queues copy of the record with resolved LogValuers, so they see the state at the time of the call.
+// The context is passed to the next handler without cancellation.
+func (h *AsyncHandler) Handle(ctx context.Context, r Record) error {
+	resolved := NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(attr Attr) bool {
+		resolved.AddAttrs(resolveAttr(attr))
+		return true
+	})
+	return h.q.push(asyncEntry{ctx: context.WithoutCancel(ctx), h: h.next, r: resolved})
+}
+
+func (h *AsyncHandler) WithAttrs(attrs []Attr) Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	resolved := make([]Attr, len(attrs))
+	for i, attr := range attrs {
+		resolved[i] = resolveAttr(attr)
+	}
+	return &AsyncHandler{next: h.next.WithAttrs(resolved), q: h.q}
+}
+
+func (h *AsyncHandler) WithGroup(name string) Handler {
+	if name == "" {
+		return h
+	}
+	return &AsyncHandler{next: h.next.WithGroup(name), q: h.q}
+}
+
+// Dropped returns the number of records dropped because the queue was full or the handler was closed
+func (h *AsyncHandler) Dropped() uint64 {
+	return h.q.dropped.Load()
+}
+
+// Flush waits until the queued records are handled
+func (h *AsyncHandler) Flush(ctx context.Context) error {
+	h.q.mu.Lock()
+	if h.q.count == 0 && !h.q.busy {
+		h.q.mu.Unlock()
+		return nil
+	}
+	if h.q.drained == nil {
+		h.q.drained = make(chan struct{})
+	}
+	drained := h.q.drained
+	h.q.mu.Unlock()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close handles the queued records and stops the background goroutine, records logged after Close are dropped
+func (h *AsyncHandler) Close() error {
+	return h.closeContext(context.Background())
+}
+
+// closeContext is Close which stops waiting when ctx is done, the records not handled yet are dropped
+func (h *AsyncHandler) closeContext(ctx context.Context) error {
+	h.q.mu.Lock()
+	h.q.closed = true
+	h.q.notEmpty.Broadcast()
+	h.q.notFull.Broadcast()
+	h.q.mu.Unlock()
+
+	select {
+	case <-h.q.done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	h.q.mu.Lock()
+	h.q.abandoned = true
+	h.q.mu.Unlock()
+
+	return ctx.Err()
+}
+
+func (q *asyncQueue) push(e asyncEntry) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.count == len(q.entries) && !q.closed {
+		switch {
+		case q.opts.Overflow == OverflowDropNewest,
+			q.opts.Overflow == OverflowDropBelowLevel && e.r.Level < q.opts.DropLevel:
+			q.dropped.Add(1)
+			return nil
+		case q.opts.Overflow == OverflowDropOldest:
+			q.entries[q.head] = asyncEntry{}
+			q.head = (q.head + 1) % len(q.entries)
+			q.count--
+			q.dropped.Add(1)
+		default:
+			for q.count == len(q.entries) && !q.closed {
+				q.notFull.Wait()
+			}
+		}
+	}
+	if q.closed {
+		q.dropped.Add(1)
+		return os.ErrClosed
+	}
+
+	q.entries[(q.head+q.count)%len(q.entries)] = e
+	q.count++
+	q.notEmpty.Signal()
+
+	return nil
+}
+
+func (q *asyncQueue) run() {
+	defer close(q.done)
+
+	for {
+		q.mu.Lock()
+		for q.count == 0 && !q.closed {
+			q.notEmpty.Wait()
+		}
+		if q.abandoned {
+			q.dropped.Add(uint64(q.count))
+			clear(q.entries)
+			q.count = 0
+		}
+		if q.count == 0 {
+			q.mu.Unlock()
+			return
+		}
+		e := q.entries[q.head]
+		q.entries[q.head] = asyncEntry{}
+		q.head = (q.head + 1) % len(q.entries)
+		q.count--
+		q.busy = true
+		q.notFull.Signal()
+		q.mu.Unlock()
+
+		// errors of the next handler can't be returned to the caller, like errors of Logger methods
+		_ = e.h.Handle(e.ctx, e.r)
+
+		q.mu.Lock()
+		q.busy = false
+		if q.count == 0 && q.drained != nil {
+			close(q.drained)
+			q.drained = nil
+		}
+		q.mu.Unlock()
+	}
+}
+
+// resolveAttr resolves LogValuers of the attribute and members of its groups
+func resolveAttr(attr Attr) Attr {
+	attr.Value = attr.Value.Resolve()
+	if attr.Value.Kind() == KindGroup {
+		members := attr.Value.Group()
+		resolved := make([]Attr, len(members))
+		for i, member := range members {
+			resolved[i] = resolveAttr(member)
+		}
+		attr.Value = GroupValue(resolved...)
+	}
+	return attr
+}
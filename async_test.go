@@ -0,0 +1,279 @@
+package glog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gateHandler records messages, Handle waits until the gate is closed
+type gateHandler struct {
+	gate    chan struct{}
+	started chan struct{}
+
+	mu       *sync.Mutex
+	messages *[]string
+	attrs    []Attr
+	ctxErrs  *[]error
+}
+
+func newGateHandler() *gateHandler {
+	return &gateHandler{
+		gate:     make(chan struct{}),
+		started:  make(chan struct{}, 100),
+		mu:       &sync.Mutex{},
+		messages: &[]string{},
+		ctxErrs:  &[]error{},
+	}
+}
+
+func (h *gateHandler) Enabled(_ context.Context, _ Level) bool { return true }
+
+func (h *gateHandler) Handle(ctx context.Context, r Record) error {
+	h.started <- struct{}{}
+	<-h.gate
+
+	msg := r.Message
+	for _, attr := range h.attrs {
+		msg += " " + attr.String()
+	}
+	r.Attrs(func(attr Attr) bool {
+		msg += " " + attr.String()
+		return true
+	})
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.messages = append(*h.messages, msg)
+	*h.ctxErrs = append(*h.ctxErrs, ctx.Err())
+	return nil
+}
+
+func (h *gateHandler) WithAttrs(attrs []Attr) Handler {
+	h2 := *h
+	h2.attrs = append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...)
+	return &h2
+}
+
+func (h *gateHandler) WithGroup(_ string) Handler { return h }
+
+func (h *gateHandler) Messages() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), *h.messages...)
+}
+
+// fillAsyncQueue logs the first record which is taken by the background goroutine and blocks it,
+// then logs records filling the queue
+func fillAsyncQueue(t *testing.T, logger *Logger, next *gateHandler, size int) {
+	t.Helper()
+	logger.Info("busy")
+	select {
+	case <-next.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the background goroutine")
+	}
+	for i := 0; i < size; i++ {
+		logger.Info("queued", IntAttr("i", i))
+	}
+}
+
+func TestAsyncHandlerOverflow(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     AsyncOptions
+		log      func(logger *Logger)
+		expected []string
+		dropped  uint64
+	}{
+		{
+			name:     "drop newest",
+			opts:     AsyncOptions{QueueSize: 2, Overflow: OverflowDropNewest},
+			log:      func(logger *Logger) { logger.Error("newest") },
+			expected: []string{"busy", "queued i=0", "queued i=1"},
+			dropped:  1,
+		},
+		{
+			name:     "drop oldest",
+			opts:     AsyncOptions{QueueSize: 2, Overflow: OverflowDropOldest},
+			log:      func(logger *Logger) { logger.Info("newest") },
+			expected: []string{"busy", "queued i=1", "newest"},
+			dropped:  1,
+		},
+		{
+			name:     "drop below level",
+			opts:     AsyncOptions{QueueSize: 2, Overflow: OverflowDropBelowLevel, DropLevel: LevelWarn},
+			log:      func(logger *Logger) { logger.Info("dropped") },
+			expected: []string{"busy", "queued i=0", "queued i=1"},
+			dropped:  1,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			next := newGateHandler()
+			h := NewAsyncHandler(next, testCase.opts)
+			logger := New(h)
+
+			fillAsyncQueue(t, logger, next, testCase.opts.QueueSize)
+			testCase.log(logger)
+			close(next.gate)
+
+			if err := h.Flush(context.Background()); err != nil {
+				t.Errorf("expected no error, got %s", err.Error())
+			}
+			if messages := next.Messages(); !reflect.DeepEqual(messages, testCase.expected) {
+				t.Errorf("expected messages %v, got %v", testCase.expected, messages)
+			}
+			if h.Dropped() != testCase.dropped {
+				t.Errorf("expected %d dropped records, got %d", testCase.dropped, h.Dropped())
+			}
+			h.Close()
+		})
+	}
+}
+
+func TestAsyncHandlerBlock(t *testing.T) {
+	for _, opts := range []AsyncOptions{
+		{QueueSize: 1, Overflow: OverflowBlock},
+		{QueueSize: 1, Overflow: OverflowDropBelowLevel, DropLevel: LevelWarn},
+	} {
+		next := newGateHandler()
+		h := NewAsyncHandler(next, opts)
+		logger := New(h)
+		fillAsyncQueue(t, logger, next, 1)
+
+		logged := make(chan struct{})
+		go func() {
+			logger.Error("blocked")
+			close(logged)
+		}()
+		select {
+		case <-logged:
+			t.Error("expected blocked caller")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(next.gate)
+		<-logged
+		h.Close()
+		if messages := next.Messages(); len(messages) != 3 || messages[2] != "blocked" || h.Dropped() != 0 {
+			t.Errorf("unexpected messages %v, dropped %d", messages, h.Dropped())
+		}
+	}
+}
+
+func TestAsyncHandlerRecords(t *testing.T) {
+	next := newGateHandler()
+	close(next.gate)
+	h := NewAsyncHandler(next, AsyncOptions{})
+
+	// attributes added to the record after hand-off must not be seen by the next handler
+	r := NewRecord(time.Now(), LevelInfo, "record", 0)
+	for i := 0; i < 6; i++ {
+		r.AddAttrs(IntAttr("a", i))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := h.WithAttrs([]Attr{StringAttr("service", "api")}).Handle(ctx, r); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+	cancel()
+	r.AddAttrs(StringAttr("late", "attr"))
+
+	if err := h.Flush(context.Background()); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+	messages := next.Messages()
+	if len(messages) != 1 || messages[0] != "record service=api a=0 a=1 a=2 a=3 a=4 a=5" {
+		t.Errorf("unexpected messages %v", messages)
+	}
+	if (*next.ctxErrs)[0] != nil {
+		t.Errorf("expected context without cancellation, got %v", (*next.ctxErrs)[0])
+	}
+
+	if h.WithGroup("") != h || h.WithAttrs(nil) != h {
+		t.Error("expected the same handler for empty group and attributes")
+	}
+
+	h.Close()
+	h.Close()
+	if err := h.Handle(context.Background(), r); !errors.Is(err, os.ErrClosed) || h.Dropped() != 1 {
+		t.Errorf("expected os.ErrClosed, got %v", err)
+	}
+}
+
+// counterValuer logs the current count, it is changed by the caller after logging
+type counterValuer struct {
+	count *int
+}
+
+func (v counterValuer) LogValue() Value { return slog.IntValue(*v.count) }
+
+func TestAsyncHandlerResolvesLogValuers(t *testing.T) {
+	next := newGateHandler()
+	h := NewAsyncHandler(next, AsyncOptions{})
+	logger := New(h)
+
+	count := 1
+	logger.Info("state", Any("count", counterValuer{&count}), Group("g", Any("count", counterValuer{&count})))
+	count = 2
+	close(next.gate)
+
+	if err := h.Flush(context.Background()); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+	h.Close()
+	if messages := next.Messages(); len(messages) != 1 || messages[0] != "state count=1 g=[count=1]" {
+		t.Errorf("unexpected messages %q", messages)
+	}
+}
+
+func TestAsyncHandlerFlushTimeout(t *testing.T) {
+	next := newGateHandler()
+	h := NewAsyncHandler(next, AsyncOptions{})
+	New(h).Info("blocked")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := h.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+
+	close(next.gate)
+	h.Close()
+	if messages := next.Messages(); len(messages) != 1 {
+		t.Errorf("expected the queued record handled on close, got %v", messages)
+	}
+}
+
+func TestBuildAsync(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, handle, err := Build(
+		WithOutputFilePath(path),
+		WithAsync(AsyncOptions{QueueSize: 16, Overflow: OverflowDropNewest}),
+		WithSetDefault(false),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+
+	for i := 0; i < 10; i++ {
+		logger.Info("async message", IntAttr("i", i))
+	}
+	logger.Debug("filtered before the queue")
+	if err := handle.Close(); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+
+	data, _ := os.ReadFile(path)
+	if count := strings.Count(string(data), "async message"); count != 10 || strings.Contains(string(data), "filtered") {
+		t.Errorf("unexpected file content %q", data)
+	}
+}
@@ -0,0 +1,191 @@
+package glog
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/docker/go-units"
+)
+
+// Config is a declarative logger configuration which can be unmarshaled from
+// JSON or YAML service configuration files
+type Config struct {
+	Level          Level             `json:"level" yaml:"level"`
+	Levels         map[string]Level  `json:"levels,omitempty" yaml:"levels,omitempty"`
+	Format         OutputFormat      `json:"format" yaml:"format"`
+	AddSource      *bool             `json:"add_source,omitempty" yaml:"add_source,omitempty"`
+	File           string            `json:"file,omitempty" yaml:"file,omitempty"`
+	Rotation       *RotationConfig   `json:"rotation,omitempty" yaml:"rotation,omitempty"`
+	ReopenOnSIGHUP bool              `json:"reopen_on_sighup,omitempty" yaml:"reopen_on_sighup,omitempty"`
+	Sinks          []SinkConfig      `json:"sinks,omitempty" yaml:"sinks,omitempty"`
+	Resource       map[string]string `json:"resource,omitempty" yaml:"resource,omitempty"`
+	OTLP           *OTLPConfig       `json:"otlp,omitempty" yaml:"otlp,omitempty"`
+	Sampling       *SamplingConfig   `json:"sampling,omitempty" yaml:"sampling,omitempty"`
+	RateLimits     []RateLimitConfig `json:"rate_limits,omitempty" yaml:"rate_limits,omitempty"`
+	DedupWindow    Duration          `json:"dedup_window,omitempty" yaml:"dedup_window,omitempty"`
+	TraceAttrs     bool              `json:"trace_attrs,omitempty" yaml:"trace_attrs,omitempty"`
+	SetDefault     *bool             `json:"set_default,omitempty" yaml:"set_default,omitempty"`
+}
+
+// RotationConfig is a declarative configuration of log file rotation, see RotationOptions
+type RotationConfig struct {
+	MaxSize    ByteSize `json:"max_size,omitempty" yaml:"max_size,omitempty"`
+	MaxAge     Duration `json:"max_age,omitempty" yaml:"max_age,omitempty"`
+	MaxBackups int      `json:"max_backups,omitempty" yaml:"max_backups,omitempty"`
+	Compress   bool     `json:"compress,omitempty" yaml:"compress,omitempty"`
+	LocalTime  bool     `json:"local_time,omitempty" yaml:"local_time,omitempty"`
+}
+
+// OTLPConfig is a declarative configuration of OTLP/HTTP export, see OTLPExporterOptions
+type OTLPConfig struct {
+	Headers      map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	BatchSize    int               `json:"batch_size,omitempty" yaml:"batch_size,omitempty"`
+	Interval     Duration          `json:"interval,omitempty" yaml:"interval,omitempty"`
+	Timeout      Duration          `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	MaxQueueSize int               `json:"max_queue_size,omitempty" yaml:"max_queue_size,omitempty"`
+}
+
+// SamplingConfig is a declarative configuration of sampling, see SamplingOptions
+type SamplingConfig struct {
+	Interval   Duration `json:"interval,omitempty" yaml:"interval,omitempty"`
+	First      int      `json:"first" yaml:"first"`
+	Thereafter int      `json:"thereafter" yaml:"thereafter"`
+}
+
+// RateLimitConfig is a declarative configuration of a rate limit, see RateLimit
+type RateLimitConfig struct {
+	Name  string  `json:"name" yaml:"name"`
+	Level Level   `json:"level" yaml:"level"`
+	Rate  float64 `json:"rate" yaml:"rate"`
+	Burst int     `json:"burst,omitempty" yaml:"burst,omitempty"`
+}
+
+// SinkConfig is a declarative configuration of a sink, see WithSink
+type SinkConfig struct {
+	Format      OutputFormat `json:"format" yaml:"format"`
+	Destination string       `json:"destination" yaml:"destination"`
+	Level       Level        `json:"level" yaml:"level"`
+}
+
+// Options returns logger options matching the configuration
+func (c Config) Options() []LoggerOption {
+	opts := []LoggerOption{
+		func(o *LoggerOptions) { o.Level = c.Level },
+		WithOutputFormat(c.Format),
+		WithOutputFilePath(c.File),
+		WithReopenOnSIGHUP(c.ReopenOnSIGHUP),
+	}
+
+	for name, level := range c.Levels {
+		opts = append(opts, WithNameLevel(name, level))
+	}
+	if c.AddSource != nil {
+		opts = append(opts, WithAddSource(*c.AddSource))
+	}
+	if c.Rotation != nil {
+		opts = append(
+			opts,
+			WithMaxSize(int64(c.Rotation.MaxSize)),
+			WithMaxAge(time.Duration(c.Rotation.MaxAge)),
+			WithMaxBackups(c.Rotation.MaxBackups),
+			WithCompress(c.Rotation.Compress),
+			WithLocalTime(c.Rotation.LocalTime),
+		)
+	}
+	for _, sink := range c.Sinks {
+		opts = append(opts, WithSink(sink.Format, sink.Destination, sink.Level))
+	}
+	names := make([]string, 0, len(c.Resource))
+	for name := range c.Resource {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		opts = append(opts, WithResource(StringAttr(name, c.Resource[name])))
+	}
+	if c.OTLP != nil {
+		opts = append(opts, WithOTLPExporter(OTLPExporterOptions{
+			Headers:      c.OTLP.Headers,
+			BatchSize:    c.OTLP.BatchSize,
+			Interval:     time.Duration(c.OTLP.Interval),
+			Timeout:      time.Duration(c.OTLP.Timeout),
+			MaxQueueSize: c.OTLP.MaxQueueSize,
+		}))
+	}
+	if c.Sampling != nil {
+		opts = append(opts, WithSampling(SamplingOptions{
+			Interval:   time.Duration(c.Sampling.Interval),
+			First:      c.Sampling.First,
+			Thereafter: c.Sampling.Thereafter,
+		}))
+	}
+	for _, limit := range c.RateLimits {
+		opts = append(opts, WithRateLimit(RateLimit(limit)))
+	}
+	if c.DedupWindow > 0 {
+		opts = append(opts, WithDedup(time.Duration(c.DedupWindow)))
+	}
+	if c.TraceAttrs {
+		opts = append(opts, WithTraceAttrs(true))
+	}
+	if c.SetDefault != nil {
+		opts = append(opts, WithSetDefault(*c.SetDefault))
+	}
+
+	return opts
+}
+
+// Build creates a logger from the configuration, extra options are applied after the configuration ones
+func (c Config) Build(opts ...LoggerOption) (*Logger, *Handle, error) {
+	return Build(append(c.Options(), opts...)...)
+}
+
+// Duration is a time.Duration written as a string like "10m" in configuration files
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(data []byte) error {
+	v, err := time.ParseDuration(string(data))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// ByteSize is a size in bytes written as a number or a string like "100MB" in configuration files,
+// units are powers of 1024
+type ByteSize int64
+
+func (s ByteSize) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatInt(int64(s), 10)), nil
+}
+
+func (s *ByteSize) UnmarshalText(data []byte) error {
+	v, err := units.RAMInBytes(string(data))
+	if err != nil {
+		return err
+	}
+	*s = ByteSize(v)
+	return nil
+}
+
+func (s *ByteSize) UnmarshalJSON(data []byte) error {
+	var v int64
+	if err := json.Unmarshal(data, &v); err == nil {
+		*s = ByteSize(v)
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("byte size must be a number or a string: %w", err)
+	}
+	return s.UnmarshalText([]byte(str))
+}
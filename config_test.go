@@ -0,0 +1,281 @@
+package glog
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigUnmarshalAndBuild(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	data := `{
+		"level": "debug",
+		"levels": {"noisy": "ERROR"},
+		"format": "json",
+		"add_source": false,
+		"set_default": false,
+		"rotation": {"max_size": "10MB", "max_age": "168h", "max_backups": 3, "compress": true},
+		"sinks": [
+			{"format": "json", "destination": "` + path + `", "level": "debug"},
+			{"format": "text", "destination": "stderr", "level": "warn"}
+		]
+	}`
+
+	var config Config
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+
+	if config.Level != LevelDebug || config.Levels["noisy"] != LevelError || config.Format != OutputFormatJSON {
+		t.Errorf("unexpected config %+v", config)
+	}
+	expected := RotationConfig{MaxSize: 10 * 1024 * 1024, MaxAge: Duration(168 * time.Hour), MaxBackups: 3, Compress: true}
+	if *config.Rotation != expected {
+		t.Errorf("expected rotation %+v, got %+v", expected, *config.Rotation)
+	}
+	if len(config.Sinks) != 2 || config.Sinks[1].Format != OutputFormatTEXT || config.Sinks[1].Level != LevelWarn {
+		t.Errorf("unexpected sinks %+v", config.Sinks)
+	}
+
+	logger, handle, err := config.Build()
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if Default() == logger {
+		t.Error("logger should NOT be default logger")
+	}
+	logger.Debug("debug message")
+	WithName(logger, "noisy").Warn("dropped")
+	handle.Close()
+
+	output, _ := os.ReadFile(path)
+	if !strings.Contains(string(output), `"msg":"debug message"`) || strings.Contains(string(output), "dropped") {
+		t.Errorf("wrong logs output data %q", output)
+	}
+}
+
+func TestConfigRoundTrip(t *testing.T) {
+	addSource := true
+	config := Config{
+		Level:     LevelWarn,
+		Levels:    map[string]Level{"http-access": LevelDebug},
+		Format:    OutputFormatTEXT,
+		AddSource: &addSource,
+		File:      "/var/log/app.log",
+		Rotation:  &RotationConfig{MaxSize: 1536, MaxAge: Duration(90 * time.Minute), LocalTime: true},
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	for _, part := range []string{`"level":"WARN"`, `"format":"text"`, `"max_age":"1h30m0s"`, `"max_size":"1536"`} {
+		if !strings.Contains(string(data), part) {
+			t.Errorf("expected %s in %s", part, data)
+		}
+	}
+
+	var decoded Config
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if !reflect.DeepEqual(config, decoded) {
+		t.Errorf("expected %+v, got %+v", config, decoded)
+	}
+}
+
+func TestConfigInvalid(t *testing.T) {
+	testCases := []string{
+		`{"format": "xml"}`,
+		`{"level": "verbose"}`,
+		`{"rotation": {"max_age": "week"}}`,
+		`{"rotation": {"max_size": "big"}}`,
+		`{"rotation": {"max_size": true}}`,
+	}
+	for _, data := range testCases {
+		var config Config
+		if err := json.Unmarshal([]byte(data), &config); err == nil {
+			t.Errorf("expected error for %s", data)
+		}
+	}
+
+	if _, err := json.Marshal(Config{Format: OutputFormat(100)}); err == nil {
+		t.Error("expected error for unknown output format")
+	}
+
+	setDefault := false
+	config := Config{File: "stdout", Sinks: []SinkConfig{{Destination: "stdout"}}, SetDefault: &setDefault}
+	if _, _, err := config.Build(); err == nil {
+		t.Error("expected error for sinks with log file path")
+	}
+}
+
+// unmarshalConfig decodes the configuration and checks that it is encoded and decoded again unchanged
+func unmarshalConfig(t *testing.T, data string) Config {
+	t.Helper()
+	var config Config
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+
+	encoded, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	var decoded Config
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if !reflect.DeepEqual(config, decoded) {
+		t.Errorf("expected %+v, got %+v", config, decoded)
+	}
+	return config
+}
+
+func TestConfigResource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "otlp.jsonl")
+	config := unmarshalConfig(t, `{
+		"format": "otlp",
+		"file": "`+path+`",
+		"set_default": false,
+		"resource": {"service.name": "api", "deployment.environment": "prod"},
+		"otlp": {"headers": {"Authorization": "Bearer token"}, "batch_size": 100, "interval": "5s", "timeout": "30s", "max_queue_size": 1000}
+	}`)
+
+	expected := OTLPConfig{
+		Headers:      map[string]string{"Authorization": "Bearer token"},
+		BatchSize:    100,
+		Interval:     Duration(5 * time.Second),
+		Timeout:      Duration(30 * time.Second),
+		MaxQueueSize: 1000,
+	}
+	if config.Resource["service.name"] != "api" || config.OTLP == nil || !reflect.DeepEqual(*config.OTLP, expected) {
+		t.Errorf("unexpected config %+v", config)
+	}
+
+	logger, handle, err := config.Build()
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	logger.Info("exported")
+	handle.Close()
+
+	output, _ := os.ReadFile(path)
+	if !strings.Contains(string(output), `{"key":"deployment.environment","value":{"stringValue":"prod"}}`) {
+		t.Errorf("expected resource in output %q", output)
+	}
+}
+
+func TestConfigSampling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	config := unmarshalConfig(t, `{
+		"file": "`+path+`",
+		"set_default": false,
+		"sampling": {"interval": "1h", "first": 2, "thereafter": 0}
+	}`)
+	if config.Sampling == nil || *config.Sampling != (SamplingConfig{Interval: Duration(time.Hour), First: 2}) {
+		t.Errorf("unexpected sampling %+v", config.Sampling)
+	}
+
+	logger, handle, err := config.Build()
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	for i := 0; i < 5; i++ {
+		logger.Info("busy")
+	}
+	handle.Close()
+
+	output, _ := os.ReadFile(path)
+	if n := strings.Count(string(output), `"msg":"busy"`); n != 2 {
+		t.Errorf("expected 2 sampled records, got %d in %q", n, output)
+	}
+}
+
+func TestConfigRateLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	config := unmarshalConfig(t, `{
+		"file": "`+path+`",
+		"set_default": false,
+		"rate_limits": [{"name": "noisy", "level": "info", "rate": 0.5, "burst": 2}]
+	}`)
+	expected := []RateLimitConfig{{Name: "noisy", Level: LevelInfo, Rate: 0.5, Burst: 2}}
+	if !reflect.DeepEqual(config.RateLimits, expected) {
+		t.Errorf("expected rate limits %+v, got %+v", expected, config.RateLimits)
+	}
+
+	logger, handle, err := config.Build()
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if limits := handle.RateLimits().Limits(); len(limits) != 1 || limits[0] != RateLimit(expected[0]) {
+		t.Errorf("unexpected limits %+v", limits)
+	}
+	for i := 0; i < 5; i++ {
+		WithName(logger, "noisy").Info("busy")
+	}
+	handle.Close()
+
+	output, _ := os.ReadFile(path)
+	if n := strings.Count(string(output), `"msg":"busy"`); n != 2 || !strings.Contains(string(output), "3 records suppressed") {
+		t.Errorf("expected 2 passed records and the suppressed count, got %q", output)
+	}
+}
+
+func TestConfigDedup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	config := unmarshalConfig(t, `{
+		"file": "`+path+`",
+		"set_default": false,
+		"dedup_window": "1m"
+	}`)
+	if config.DedupWindow != Duration(time.Minute) {
+		t.Errorf("expected 1m dedup window, got %s", time.Duration(config.DedupWindow))
+	}
+
+	logger, handle, err := config.Build()
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	for i := 0; i < 3; i++ {
+		logger.Info("repeated")
+	}
+	handle.Close()
+
+	output, _ := os.ReadFile(path)
+	if n := strings.Count(string(output), `"msg":"repeated"`); n != 2 || !strings.Contains(string(output), `"repeated":2`) {
+		t.Errorf("expected the record and its repetition summary, got %q", output)
+	}
+}
+
+func TestConfigTraceAttrs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	config := unmarshalConfig(t, `{
+		"file": "`+path+`",
+		"set_default": false,
+		"trace_attrs": true
+	}`)
+	if !config.TraceAttrs {
+		t.Error("expected trace attributes enabled")
+	}
+
+	logger, handle, err := config.Build()
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	tc, _ := ParseTraceParent(testTraceParent)
+	logger.InfoContext(ContextWithTraceContext(context.Background(), tc), "traced")
+	handle.Close()
+
+	output, _ := os.ReadFile(path)
+	if !strings.Contains(string(output), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`) {
+		t.Errorf("expected trace attributes in output %q", output)
+	}
+}
@@ -25,3 +25,132 @@ func LoggerFromContext(ctx context.Context) *Logger {
 
 	return GetDefault()
 }
+
+type contextAttrsKey struct{}
+
+// ContextWithAttrs returns context with the attributes appended to the attributes of ctx, they are added
+// to records logged with the context by ContextHandler. Loggers built with WithCustomHandler log them only
+// with WithTraceAttrs or when the custom handler is wrapped by NewContextHandler.
+func ContextWithAttrs(ctx context.Context, attrs ...Attr) context.Context {
+	if len(attrs) == 0 {
+		return ctx
+	}
+	parent := AttrsFromContext(ctx)
+	return context.WithValue(ctx, contextAttrsKey{}, append(parent[:len(parent):len(parent)], attrs...))
+}
+
+// AttrsFromContext returns attributes added to context by ContextWithAttrs
+func AttrsFromContext(ctx context.Context) []Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(contextAttrsKey{}).([]Attr)
+	return attrs
+}
+
+// ContextHandlerOptions configures ContextHandler
+type ContextHandlerOptions struct {
+	// TraceAttrs adds trace_id, span_id and trace_flags attributes of the trace context of the context,
+	// see ContextWithTraceContext
+	TraceAttrs bool
+}
+
+// ContextHandler adds attributes of the context added by ContextWithAttrs to records, so they are logged by
+// any logger the context is passed to, e.g. with InfoContext. The attributes are added to the record like
+// attributes passed to the logging call, loggers with open groups log them in the innermost group. Trace
+// attributes are logged at the top level, so records can be correlated by them regardless of groups.
+type ContextHandler struct {
+	// next has the attributes added before the first group, groups and their attributes are kept in groups
+	// and passed to next as group attributes of records
+	next   Handler
+	opts   ContextHandlerOptions
+	groups []contextGroup
+	// traceOnly skips attributes of ContextWithAttrs for TraceHandler
+	traceOnly bool
+}
+
+// contextGroup is a group opened by WithGroup with the attributes added in it
+type contextGroup struct {
+	name  string
+	attrs []Attr
+}
+
+func NewContextHandler(next Handler, opts ContextHandlerOptions) *ContextHandler {
+	return &ContextHandler{next: next, opts: opts}
+}
+
+func (h *ContextHandler) Enabled(ctx context.Context, level Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, r Record) error {
+	var attrs []Attr
+	if !h.traceOnly {
+		attrs = AttrsFromContext(ctx)
+	}
+	var tc TraceContext
+	traced := false
+	if h.opts.TraceAttrs {
+		tc, traced = TraceContextFromContext(ctx)
+	}
+
+	if len(h.groups) == 0 {
+		if len(attrs) == 0 && !traced {
+			return h.next.Handle(ctx, r)
+		}
+		// the record may be shared with the caller
+		r = r.Clone()
+		if traced {
+			r.AddAttrs(traceAttrs(tc)...)
+		}
+		r.AddAttrs(attrs...)
+		return h.next.Handle(ctx, r)
+	}
+
+	inner := make([]Attr, 0, r.NumAttrs()+len(attrs))
+	r.Attrs(func(attr Attr) bool {
+		inner = append(inner, attr)
+		return true
+	})
+	inner = append(inner, attrs...)
+	for i := len(h.groups) - 1; i >= 0; i-- {
+		g := h.groups[i]
+		members := append(g.attrs[:len(g.attrs):len(g.attrs)], inner...)
+		inner = nil
+		if len(members) > 0 {
+			// empty groups are omitted like by the standard handlers
+			inner = []Attr{{Key: g.name, Value: GroupValue(members...)}}
+		}
+	}
+
+	grouped := NewRecord(r.Time, r.Level, r.Message, r.PC)
+	if traced {
+		grouped.AddAttrs(traceAttrs(tc)...)
+	}
+	grouped.AddAttrs(inner...)
+	return h.next.Handle(ctx, grouped)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []Attr) Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	if len(h.groups) == 0 {
+		h2.next = h.next.WithAttrs(attrs)
+		return &h2
+	}
+	h2.groups = append([]contextGroup(nil), h.groups...)
+	last := &h2.groups[len(h2.groups)-1]
+	last.attrs = append(last.attrs[:len(last.attrs):len(last.attrs)], attrs...)
+	return &h2
+}
+
+func (h *ContextHandler) WithGroup(name string) Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], contextGroup{name: name})
+	return &h2
+}
@@ -1,8 +1,13 @@
 package glog
 
 import (
+	"bytes"
 	"context"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoggerInContext(t *testing.T) {
@@ -35,3 +40,75 @@ func TestLoggerInContext(t *testing.T) {
 	}
 
 }
+
+func TestContextWithAttrs(t *testing.T) {
+	ctx := ContextWithAttrs(context.Background(), StringAttr("request_id", "1"))
+	if ContextWithAttrs(ctx) != ctx {
+		t.Errorf("expected context without attributes to be returned unchanged")
+	}
+
+	child1 := ContextWithAttrs(ctx, StringAttr("user", "alice"))
+	child2 := ContextWithAttrs(ctx, StringAttr("user", "bob"))
+	if attrs := AttrsFromContext(child1); len(attrs) != 2 || attrs[1].Value.String() != "alice" {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+	if attrs := AttrsFromContext(child2); len(attrs) != 2 || attrs[1].Value.String() != "bob" {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+	if attrs := AttrsFromContext(ctx); len(attrs) != 1 {
+		t.Errorf("expected parent attributes unchanged, got %v", attrs)
+	}
+	if AttrsFromContext(nil) != nil {
+		t.Errorf("expected no attributes")
+	}
+}
+
+func TestContextHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(NewContextHandler(NewJSONHandler(&buf, nil), ContextHandlerOptions{}))
+	ctx := ContextWithAttrs(context.Background(), StringAttr("request_id", "1"))
+
+	logger.With(StringAttr("service", "api")).InfoContext(ContextWithAttrs(ctx, StringAttr("user", "alice")), "done", IntAttr("id", 7))
+	m := decodeJSONLine(t, &buf)
+	if m["request_id"] != "1" || m["user"] != "alice" || m["service"] != "api" || m["id"] != float64(7) {
+		t.Errorf("unexpected record %v", m)
+	}
+
+	// loggers with open groups log the attributes in the innermost group
+	logger.WithGroup("job").InfoContext(ctx, "done", IntAttr("id", 7))
+	m = decodeJSONLine(t, &buf)
+	if job, _ := m["job"].(map[string]any); job["request_id"] != "1" || job["id"] != float64(7) {
+		t.Errorf("unexpected grouped record %v", m)
+	}
+
+	logger.WithGroup("job").Info("done", IntAttr("id", 7))
+	if m := decodeJSONLine(t, &buf); m["request_id"] != nil || m["job"] == nil {
+		t.Errorf("unexpected record without context attributes %v", m)
+	}
+
+	// the record passed to the handler is not modified
+	r := NewRecord(time.Now(), LevelInfo, "done", 0)
+	r.AddAttrs(IntAttr("a", 1), IntAttr("b", 2), IntAttr("c", 3), IntAttr("d", 4), IntAttr("e", 5), IntAttr("f", 6))
+	logger.Handler().Handle(ctx, r)
+	decodeJSONLine(t, &buf)
+	if r.NumAttrs() != 6 {
+		t.Errorf("expected 6 attributes, got %d", r.NumAttrs())
+	}
+}
+
+func TestBuildContextAttrs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, handle, err := Build(WithOutputFilePath(path), WithSetDefault(false))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+
+	ctx := ContextWithAttrs(context.Background(), StringAttr("tenant", "acme"))
+	WithName(logger, "worker").InfoContext(ctx, "started")
+	handle.Close()
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"name":"worker","tenant":"acme"`) {
+		t.Errorf("unexpected file content %s", data)
+	}
+}
@@ -0,0 +1,223 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// dedupMaxEntries limits the number of open windows, records are not suppressed while the limit is reached
+const dedupMaxEntries = 10000
+
+// DedupHandler suppresses records repeating a record with the same level, message and attributes within
+// the window started by the first record. When the window closes, the record is logged once more with
+// the number of suppressed repeats in the "repeated" attribute and the times of the first and the last
+// one in the "first" and "last" attributes. Handlers derived by WithAttrs and WithGroup share the state.
+type DedupHandler struct {
+	next Handler
+	// scope is the encoding of attributes and groups of the handler, it is a part of the record key
+	scope []byte
+	d     *dedupState
+}
+
+type dedupEntry struct {
+	key      []byte
+	sum      uint64
+	ctx      context.Context
+	h        Handler
+	r        Record
+	first    time.Time
+	last     time.Time
+	repeated uint64
+	expires  time.Time
+}
+
+type dedupState struct {
+	window time.Duration
+	sum    func(key []byte) uint64
+
+	mu      sync.Mutex
+	entries map[uint64]*dedupEntry
+	// queue holds the entries in the order of expiration, the timer fires when the first one expires
+	queue  []*dedupEntry
+	timer  *time.Timer
+	closed bool
+}
+
+// NewDedupHandler creates handler suppressing repeated records within the window
+func NewDedupHandler(next Handler, window time.Duration) *DedupHandler {
+	return &DedupHandler{
+		next: next,
+		d:    &dedupState{window: window, sum: fnvSum64, entries: make(map[uint64]*dedupEntry)},
+	}
+}
+
+func (h *DedupHandler) Enabled(ctx context.Context, level Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *DedupHandler) Handle(ctx context.Context, r Record) error {
+	key := h.key(r)
+	sum := h.d.sum(key)
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	h.d.mu.Lock()
+	if e, ok := h.d.entries[sum]; ok {
+		if bytes.Equal(e.key, key) {
+			e.repeated++
+			e.last = t
+			h.d.mu.Unlock()
+			return nil
+		}
+		// records with colliding hashes are logged and not tracked
+	} else if !h.d.closed && len(h.d.entries) < dedupMaxEntries {
+		e := &dedupEntry{key: key, sum: sum, ctx: context.WithoutCancel(ctx), h: h.next, r: r.Clone(),
+			first: t, last: t, expires: time.Now().Add(h.d.window)}
+		h.d.entries[sum] = e
+		h.d.queue = append(h.d.queue, e)
+		if h.d.timer == nil {
+			h.d.timer = time.AfterFunc(h.d.window, h.d.sweep)
+		} else if len(h.d.queue) == 1 {
+			h.d.timer.Reset(h.d.window)
+		}
+	}
+	h.d.mu.Unlock()
+
+	return h.next.Handle(ctx, r)
+}
+
+func (h *DedupHandler) WithAttrs(attrs []Attr) Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.scope = h.scope[:len(h.scope):len(h.scope)]
+	for _, attr := range attrs {
+		h2.scope = appendDedupAttr(h2.scope, attr)
+	}
+	h2.next = h.next.WithAttrs(attrs)
+	return &h2
+}
+
+func (h *DedupHandler) WithGroup(name string) Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.scope = appendDedupString(append(h.scope[:len(h.scope):len(h.scope)], 'g'), name)
+	h2.next = h.next.WithGroup(name)
+	return &h2
+}
+
+// Flush logs records repeated in the open windows and closes the windows
+func (h *DedupHandler) Flush(_ context.Context) error {
+	return h.d.expireAll()
+}
+
+// Close logs records repeated in the open windows, records logged after Close are not suppressed
+func (h *DedupHandler) Close() error {
+	h.d.mu.Lock()
+	h.d.closed = true
+	h.d.mu.Unlock()
+
+	return h.d.expireAll()
+}
+
+// key returns unambiguous encoding of the scope, level, message and attributes of the record
+func (h *DedupHandler) key(r Record) []byte {
+	buf := append([]byte(nil), h.scope...)
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(r.Level))
+	buf = appendDedupString(buf, r.Message)
+	r.Attrs(func(attr Attr) bool {
+		buf = appendDedupAttr(buf, attr)
+		return true
+	})
+	return buf
+}
+
+func fnvSum64(key []byte) uint64 {
+	hash := fnv.New64a()
+	hash.Write(key)
+	return hash.Sum64()
+}
+
+// sweep removes the expired entries, logs the repeated records and rearms the timer for the next entry
+func (d *dedupState) sweep() {
+	d.mu.Lock()
+	now := time.Now()
+	i := 0
+	for i < len(d.queue) && !d.queue[i].expires.After(now) {
+		delete(d.entries, d.queue[i].sum)
+		i++
+	}
+	expired := append([]*dedupEntry(nil), d.queue[:i]...)
+	n := copy(d.queue, d.queue[i:])
+	clear(d.queue[n:])
+	d.queue = d.queue[:n]
+	if n > 0 {
+		d.timer.Reset(d.queue[0].expires.Sub(now))
+	}
+	d.mu.Unlock()
+
+	for _, e := range expired {
+		_ = e.emit()
+	}
+}
+
+func (d *dedupState) expireAll() error {
+	d.mu.Lock()
+	queue := d.queue
+	d.entries = make(map[uint64]*dedupEntry)
+	d.queue = nil
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.mu.Unlock()
+
+	var err error
+	for _, e := range queue {
+		if e2 := e.emit(); e2 != nil {
+			err = e2
+		}
+	}
+	return err
+}
+
+func (e *dedupEntry) emit() error {
+	if e.repeated == 0 {
+		return nil
+	}
+
+	r := e.r.Clone()
+	r.Time = e.last
+	r.AddAttrs(Uint64Attr("repeated", e.repeated), Time("first", e.first), Time("last", e.last))
+	return e.h.Handle(e.ctx, r)
+}
+
+func appendDedupString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// appendDedupAttr appends unambiguous encoding of the attribute
+func appendDedupAttr(buf []byte, attr Attr) []byte {
+	attr.Value = attr.Value.Resolve()
+	buf = appendDedupString(buf, attr.Key)
+	buf = append(buf, byte(attr.Value.Kind()))
+
+	if attr.Value.Kind() == KindGroup {
+		members := attr.Value.Group()
+		buf = binary.AppendUvarint(buf, uint64(len(members)))
+		for _, member := range members {
+			buf = appendDedupAttr(buf, member)
+		}
+		return buf
+	}
+	return appendDedupString(buf, attr.Value.String())
+}
@@ -0,0 +1,165 @@
+package glog
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDedupHandler(t *testing.T) {
+	var records []Record
+	h := NewDedupHandler(NewRecordsHandler(&records), time.Hour)
+	logger := New(h)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		logger.Error("connect failed", ErrAttr(errors.New("refused")), Group("retry", IntAttr("max", 3)))
+	}
+	logger.Error("connect failed", ErrAttr(errors.New("timeout")))
+	logger.Warn("connect failed", ErrAttr(errors.New("refused")), Group("retry", IntAttr("max", 3)))
+	logger.WithGroup("db").Error("connect failed", ErrAttr(errors.New("refused")), Group("retry", IntAttr("max", 3)))
+
+	if len(records) != 4 {
+		t.Fatalf("expected 4 records, got %d", len(records))
+	}
+
+	records = nil
+	if err := h.Flush(context.Background()); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 repeated record, got %d", len(records))
+	}
+
+	r := records[0]
+	attrs := map[string]Value{}
+	r.Attrs(func(attr Attr) bool {
+		attrs[attr.Key] = attr.Value
+		return true
+	})
+	if r.Message != "connect failed" || r.Level != LevelError || attrs["repeated"].Uint64() != 3 || attrs["error"].String() != "refused" {
+		t.Errorf("unexpected repeated record %s %v", r.Message, attrs)
+	}
+	first, last := attrs["first"].Time(), attrs["last"].Time()
+	if first.Before(start) || last.Before(first) || !r.Time.Equal(last) {
+		t.Errorf("unexpected timestamps first %s, last %s, record %s", first, last, r.Time)
+	}
+
+	// the window is closed by Flush, the next record is logged
+	records = nil
+	logger.Error("connect failed", ErrAttr(errors.New("refused")), Group("retry", IntAttr("max", 3)))
+	h.Close()
+	logger.Error("connect failed", ErrAttr(errors.New("refused")), Group("retry", IntAttr("max", 3)))
+	if len(records) != 2 {
+		t.Errorf("expected records logged after the window and after close, got %d", len(records))
+	}
+}
+
+func TestDedupHandlerWindow(t *testing.T) {
+	next := newGateHandler()
+	close(next.gate)
+	h := NewDedupHandler(next, 20*time.Millisecond)
+	defer h.Close()
+	logger := New(h).With(StringAttr("service", "api"))
+
+	logger.Info("retry", IntAttr("attempt", 1))
+	logger.Info("retry", IntAttr("attempt", 1))
+	New(h).Info("retry", IntAttr("attempt", 1))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(next.Messages()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	messages := next.Messages()
+	if len(messages) != 3 || !strings.HasPrefix(messages[2], "retry service=api attempt=1 repeated=1 first=") {
+		t.Errorf("unexpected messages %q", messages)
+	}
+
+	// the window of a record without repeats closes silently
+	time.Sleep(50 * time.Millisecond)
+	if messages := next.Messages(); len(messages) != 3 {
+		t.Errorf("unexpected messages %q", messages)
+	}
+}
+
+// dedupHash returns the hash the handler tracks the record by
+func dedupHash(h Handler, r Record) uint64 {
+	d := h.(*DedupHandler)
+	return d.d.sum(d.key(r))
+}
+
+func TestDedupHash(t *testing.T) {
+	h := NewDedupHandler(NewDiscardHandler(), time.Second)
+	record := func(attrs ...Attr) Record {
+		r := NewRecord(time.Now(), LevelInfo, "message", 0)
+		r.AddAttrs(attrs...)
+		return r
+	}
+
+	same := dedupHash(h, record(StringAttr("a", "b"))) == dedupHash(h, record(StringAttr("a", "b")))
+	different := []bool{
+		dedupHash(h, record(StringAttr("a", "bc"))) == dedupHash(h, record(StringAttr("ab", "c"))),
+		dedupHash(h, record(StringAttr("a", "1"))) == dedupHash(h, record(IntAttr("a", 1))),
+		dedupHash(h, record(Group("g", StringAttr("a", "b")))) == dedupHash(h, record(StringAttr("g", "a=b"))),
+		dedupHash(h, record()) == dedupHash(h.WithGroup("g"), record()),
+	}
+	if !same || !reflect.DeepEqual(different, []bool{false, false, false, false}) {
+		t.Errorf("unexpected hash equality %v %v", same, different)
+	}
+}
+
+func TestDedupHandlerLimits(t *testing.T) {
+	var records []Record
+	h := NewDedupHandler(NewRecordsHandler(&records), time.Hour)
+	logger := New(h)
+
+	// a record with a colliding hash is not suppressed and does not close the window
+	h.d.sum = func([]byte) uint64 { return 1 }
+	logger.Info("first")
+	logger.Info("second")
+	logger.Info("first")
+	logger.Info("second")
+	if len(records) != 3 || records[2].Message != "second" {
+		t.Fatalf("unexpected records %v", records)
+	}
+	h.Flush(context.Background())
+	if len(records) != 4 || records[3].Message != "first" {
+		t.Errorf("expected repeated first record, got %v", records)
+	}
+
+	// records are not suppressed while the number of windows is limited
+	h.d.sum = fnvSum64
+	for i := 0; i < dedupMaxEntries; i++ {
+		logger.Info("message", IntAttr("i", i))
+	}
+	records = nil
+	logger.Info("extra")
+	logger.Info("extra")
+	if len(records) != 2 || len(h.d.entries) != dedupMaxEntries {
+		t.Errorf("expected 2 extra records and %d windows, got %d and %d", dedupMaxEntries, len(records), len(h.d.entries))
+	}
+	h.Close()
+}
+
+func TestBuildDedup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	config := Config{File: path, DedupWindow: Duration(time.Hour)}
+	logger, handle, err := config.Build(WithSetDefault(false))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	for i := 0; i < 3; i++ {
+		logger.Warn("disk almost full")
+	}
+	handle.Close()
+
+	data, _ := os.ReadFile(path)
+	if strings.Count(string(data), "disk almost full") != 2 || !strings.Contains(string(data), `"repeated":2`) {
+		t.Errorf("unexpected file content %q", data)
+	}
+}
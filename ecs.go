@@ -0,0 +1,166 @@
+package glog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+)
+
+const ecsVersion = "8.11.0"
+
+// ecsFieldNames maps top level attribute keys to ECS field names
+var ecsFieldNames = map[string]string{
+	NameKey: "log.logger",
+	"error": "error.message",
+}
+
+// ecsAccessLogFieldNames maps access log fields to ECS field names, they are not applied to other records,
+// as the keys are generic and values of other types would be rejected
+var ecsAccessLogFieldNames = map[AccessLogField]string{
+	AccessLogFieldMethod:  "http.request.method",
+	AccessLogFieldStatus:  "http.response.status_code",
+	AccessLogFieldLength:  "http.response.body.bytes",
+	AccessLogFieldReferer: "http.request.referrer",
+	AccessLogFieldIP:      "client.ip",
+	AccessLogFieldAgent:   "user_agent.original",
+	AccessLogFieldQuery:   "url.original",
+}
+
+// NewECSHandler creates a JSON handler emitting Elastic Common Schema field names: @timestamp, log.level,
+// message, log.origin.* for the source and ECS names for the top level attributes of records logged by the
+// access log middleware, e.g. http.request.method, client.ip and event.duration in nanoseconds, also for
+// keys renamed with WithAccessLogKey. ReplaceAttr of opts is applied before the ECS mapping, it gets
+// the default keys of access log fields.
+func NewECSHandler(w io.Writer, opts *HandlerOptions) Handler {
+	var o HandlerOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	newHandler := func(accessLog bool) Handler {
+		rep := o.ReplaceAttr
+		ho := o
+		ho.ReplaceAttr = func(groups []string, a Attr) Attr {
+			if rep != nil {
+				a = rep(groups, a)
+			}
+			if len(groups) > 0 {
+				return a
+			}
+			return ecsAttr(a, accessLog)
+		}
+		return NewJSONHandler(w, &ho).WithAttrs([]Attr{StringAttr("ecs.version", ecsVersion)})
+	}
+
+	return &ecsHandler{Handler: newHandler(false), accessLog: newHandler(true)}
+}
+
+// ecsHandler passes records of the access log middleware to the handler mapping HTTP fields
+type ecsHandler struct {
+	Handler
+	accessLog Handler
+}
+
+func (h *ecsHandler) Handle(ctx context.Context, r Record) error {
+	fields, ok := accessLogFieldsFromContext(ctx)
+	if !ok {
+		return h.Handler.Handle(ctx, r)
+	}
+	return h.accessLog.Handle(ctx, accessLogFieldRecord(r, fields))
+}
+
+// accessLogFieldRecord returns the access log record with renamed attribute keys of fields set back to
+// the default keys
+func accessLogFieldRecord(r Record, fields map[string]AccessLogField) Record {
+	renamed := false
+	for key, field := range fields {
+		if key != string(field) {
+			renamed = true
+			break
+		}
+	}
+	if !renamed {
+		return r
+	}
+
+	r2 := NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a Attr) bool {
+		if field, ok := fields[a.Key]; ok {
+			a.Key = string(field)
+		}
+		r2.AddAttrs(a)
+		return true
+	})
+	return r2
+}
+
+func (h *ecsHandler) WithAttrs(attrs []Attr) Handler {
+	return &ecsHandler{Handler: h.Handler.WithAttrs(attrs), accessLog: h.accessLog.WithAttrs(attrs)}
+}
+
+func (h *ecsHandler) WithGroup(name string) Handler {
+	return &ecsHandler{Handler: h.Handler.WithGroup(name), accessLog: h.accessLog.WithGroup(name)}
+}
+
+func ecsAttr(a Attr, accessLog bool) Attr {
+	switch a.Key {
+	case TimeKey:
+		a.Key = "@timestamp"
+		if a.Value.Kind() == KindTime {
+			a.Value = slog.TimeValue(a.Value.Time().UTC())
+		}
+		return a
+	case LevelKey:
+		if level, ok := a.Value.Any().(Level); ok {
+			return StringAttr("log.level", strings.ToLower(level.String()))
+		}
+		a.Key = "log.level"
+		return a
+	case MessageKey:
+		a.Key = "message"
+		return a
+	case SourceKey:
+		if src, ok := a.Value.Any().(*Source); ok {
+			return Group(
+				"",
+				StringAttr("log.origin.file.name", src.File),
+				IntAttr("log.origin.file.line", src.Line),
+				StringAttr("log.origin.function", src.Function),
+			)
+		}
+		return a
+	}
+
+	if name, ok := ecsFieldNames[a.Key]; ok {
+		a.Key = name
+		return a
+	}
+	if !accessLog {
+		return a
+	}
+	if a.Key == string(AccessLogFieldDuration) {
+		if d, ok := ecsDuration(a.Value); ok {
+			return Int64Attr("event.duration", d)
+		}
+		return a
+	}
+	if name, ok := ecsAccessLogFieldNames[AccessLogField(a.Key)]; ok {
+		a.Key = name
+	}
+	return a
+}
+
+// ecsDuration returns duration in nanoseconds, float values are seconds as logged by the access log middleware
+func ecsDuration(v Value) (int64, bool) {
+	switch v.Kind() {
+	case KindDuration:
+		return v.Duration().Nanoseconds(), true
+	case KindFloat64:
+		return int64(math.Round(v.Float64() * float64(time.Second))), true
+	default:
+		return 0, false
+	}
+}
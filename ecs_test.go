@@ -0,0 +1,130 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func decodeJSONLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	var m map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("invalid json %q: %s", buf.String(), err.Error())
+	}
+	buf.Reset()
+	return m
+}
+
+func TestECSHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(NewECSHandler(&buf, &HandlerOptions{AddSource: true}))
+
+	WithName(logger, "worker").Warn("job failed", ErrAttr(errors.New("timeout")), Group("job", StringAttr("status", "failed")))
+	m := decodeJSONLine(t, &buf)
+
+	expected := map[string]any{
+		"ecs.version":   ecsVersion,
+		"log.level":     "warn",
+		"message":       "job failed",
+		"log.logger":    "worker",
+		"error.message": "timeout",
+	}
+	for key, value := range expected {
+		if m[key] != value {
+			t.Errorf("expected %s=%v, got %v", key, value, m[key])
+		}
+	}
+	if ts, ok := m["@timestamp"].(string); !ok || !strings.HasSuffix(ts, "Z") {
+		t.Errorf("expected UTC @timestamp, got %v", m["@timestamp"])
+	}
+	if file, ok := m["log.origin.file.name"].(string); !ok || !strings.HasSuffix(file, "ecs_test.go") {
+		t.Errorf("unexpected log.origin.file.name %v", m["log.origin.file.name"])
+	}
+	if _, ok := m["log.origin.file.line"].(float64); !ok {
+		t.Errorf("unexpected log.origin.file.line %v", m["log.origin.file.line"])
+	}
+	if fn, ok := m["log.origin.function"].(string); !ok || !strings.HasSuffix(fn, "TestECSHandler") {
+		t.Errorf("unexpected log.origin.function %v", m["log.origin.function"])
+	}
+	for _, key := range []string{"time", "level", "msg", "source", "name", "error"} {
+		if _, ok := m[key]; ok {
+			t.Errorf("unexpected %s field", key)
+		}
+	}
+	// attributes of groups are not renamed
+	if job, ok := m["job"].(map[string]any); !ok || job["status"] != "failed" {
+		t.Errorf("unexpected job group %v", m["job"])
+	}
+
+	// HTTP fields are mapped only in access log records
+	logger.Info("job", StringAttr("status", "running"), StringAttr("duration", "long"), IntAttr("length", 3))
+	m = decodeJSONLine(t, &buf)
+	if m["status"] != "running" || m["duration"] != "long" || m["length"] != float64(3) ||
+		m["http.response.status_code"] != nil || m["event.duration"] != nil {
+		t.Errorf("unexpected record %v", m)
+	}
+}
+
+func TestECSHandlerAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(NewECSHandler(&buf, &HandlerOptions{ReplaceAttr: func(groups []string, a Attr) Attr {
+		if a.Key == "duration" {
+			return Float64Attr("duration", 0.0015)
+		}
+		return a
+	}}))
+
+	ctx := ContextWithLogger(context.Background(), logger)
+	handler := NewHttpAccessLogMiddleware("http-access")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	}))
+	req := httptest.NewRequest("POST", "http://testing/cats?id=1", nil).WithContext(ctx)
+	req.Header.Set("User-Agent", "my-test-agent")
+	req.Header.Set("Referer", "http://testing/auth")
+	req.Header.Set("X-Real-IP", "10.0.0.1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	m := decodeJSONLine(t, &buf)
+	expected := map[string]any{
+		"log.level":                 "warn",
+		"message":                   "Request",
+		"log.logger":                "http-access",
+		"http.request.method":       "POST",
+		"http.response.status_code": float64(404),
+		"http.response.body.bytes":  float64(9),
+		"http.request.referrer":     "http://testing/auth",
+		"client.ip":                 "10.0.0.1",
+		"user_agent.original":       "my-test-agent",
+		"url.original":              "/cats?id=1",
+		"event.duration":            float64(1500000),
+	}
+	for key, value := range expected {
+		if m[key] != value {
+			t.Errorf("expected %s=%v, got %v", key, value, m[key])
+		}
+	}
+
+	// renamed keys are mapped by their fields
+	handler = NewHttpAccessLogMiddleware("http-access", WithAccessLogKey(AccessLogFieldQuery, "url"),
+		WithAccessLogKey(AccessLogFieldMethod, "verb"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "http://testing/dogs", nil).WithContext(ctx))
+	m = decodeJSONLine(t, &buf)
+	if m["url.original"] != "/dogs" || m["http.request.method"] != "GET" || m["url"] != nil || m["verb"] != nil {
+		t.Errorf("unexpected record with renamed keys %v", m)
+	}
+
+	f, err := ParseOutputFormat("ECS")
+	if err != nil || f != OutputFormatECS || f.String() != "ecs" {
+		t.Errorf("expected ecs output format, got %s, err %v", f, err)
+	}
+	if _, _, err := Build(WithOutputFormat(OutputFormatECS), WithSetDefault(false)); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+}
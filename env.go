@@ -0,0 +1,112 @@
+package glog
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+)
+
+// environment variable names read by WithEnv without the prefix
+const (
+	envLevel          = "LEVEL"
+	envLevels         = "LEVELS"
+	envFormat         = "FORMAT"
+	envFile           = "FILE"
+	envAddSource      = "ADD_SOURCE"
+	envMaxSize        = "MAX_SIZE"
+	envMaxAge         = "MAX_AGE"
+	envMaxBackups     = "MAX_BACKUPS"
+	envCompress       = "COMPRESS"
+	envLocalTime      = "LOCAL_TIME"
+	envReopenOnSIGHUP = "REOPEN_ON_SIGHUP"
+)
+
+// WithEnv logger option configures the logger from environment variables with the prefix:
+//
+//	PREFIX_LEVEL             level, e.g. debug, info, warn, error
+//	PREFIX_LEVELS            per-name levels, e.g. http-access=debug,*=info
+//	PREFIX_FORMAT            output format, e.g. json, text
+//	PREFIX_FILE              log file path, stdout or stderr
+//	PREFIX_ADD_SOURCE        add source file and line, true or false
+//	PREFIX_MAX_SIZE          log file size to rotate at, e.g. 100MB
+//	PREFIX_MAX_AGE           maximum time to retain rotated files, e.g. 168h
+//	PREFIX_MAX_BACKUPS       maximum number of rotated files to retain
+//	PREFIX_COMPRESS          compress rotated files, true or false
+//	PREFIX_LOCAL_TIME        local time in rotated file names, true or false
+//	PREFIX_REOPEN_ON_SIGHUP  reopen log file on SIGHUP, true or false
+//
+// Unset variables keep options unchanged, invalid values are returned as errors by Build and NewLoggerE.
+func WithEnv(prefix string) LoggerOption {
+	return func(o *LoggerOptions) {
+		env := envReader{prefix: strings.TrimSuffix(prefix, "_"), opts: o}
+
+		env.read(envLevel, func(v string) error {
+			return o.Level.UnmarshalText([]byte(v))
+		})
+		env.read(envLevels, func(v string) error {
+			levels, err := ParseNameLevels(v)
+			for name, level := range levels {
+				WithNameLevel(name, level)(o)
+			}
+			return err
+		})
+		env.read(envFormat, func(v string) (err error) {
+			o.OutputFormat, err = ParseOutputFormat(v)
+			return err
+		})
+		env.read(envFile, func(v string) error {
+			o.LogFilePath = v
+			return nil
+		})
+		env.readBool(envAddSource, &o.AddSource)
+		env.read(envMaxSize, func(v string) (err error) {
+			o.Rotation.MaxSize, err = units.RAMInBytes(v)
+			return err
+		})
+		env.read(envMaxAge, func(v string) (err error) {
+			o.Rotation.MaxAge, err = time.ParseDuration(v)
+			return err
+		})
+		env.read(envMaxBackups, func(v string) (err error) {
+			o.Rotation.MaxBackups, err = strconv.Atoi(v)
+			return err
+		})
+		env.readBool(envCompress, &o.Rotation.Compress)
+		env.readBool(envLocalTime, &o.Rotation.LocalTime)
+		env.readBool(envReopenOnSIGHUP, &o.ReopenOnSIGHUP)
+	}
+}
+
+type envReader struct {
+	prefix string
+	opts   *LoggerOptions
+}
+
+func (e envReader) name(key string) string {
+	if e.prefix == "" {
+		return key
+	}
+	return e.prefix + "_" + key
+}
+
+func (e envReader) read(key string, parse func(v string) error) {
+	name := e.name(key)
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return
+	}
+	if err := parse(strings.TrimSpace(v)); err != nil {
+		e.opts.errs = append(e.opts.errs, fmt.Errorf("invalid %s value '%s': %w", name, v, err))
+	}
+}
+
+func (e envReader) readBool(key string, dst *bool) {
+	e.read(key, func(v string) (err error) {
+		*dst, err = strconv.ParseBool(v)
+		return err
+	})
+}
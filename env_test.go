@@ -0,0 +1,95 @@
+package glog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	t.Setenv("APP_LEVEL", "debug")
+	t.Setenv("APP_LEVELS", "noisy=error")
+	t.Setenv("APP_FORMAT", "TEXT")
+	t.Setenv("APP_FILE", path)
+	t.Setenv("APP_ADD_SOURCE", "false")
+	t.Setenv("APP_MAX_SIZE", "10MB")
+	t.Setenv("APP_MAX_AGE", "24h")
+	t.Setenv("APP_MAX_BACKUPS", "3")
+	t.Setenv("APP_COMPRESS", "true")
+	t.Setenv("APP_LOCAL_TIME", "1")
+
+	config := &LoggerOptions{AddSource: true}
+	WithEnv("APP_")(config)
+
+	if len(config.errs) != 0 {
+		t.Fatalf("expected no errors, got %v", config.errs)
+	}
+	expected := RotationOptions{MaxSize: 10 * 1024 * 1024, MaxAge: 24 * time.Hour, MaxBackups: 3, Compress: true, LocalTime: true}
+	if config.Rotation != expected {
+		t.Errorf("expected rotation options %+v, got %+v", expected, config.Rotation)
+	}
+	if config.Level != LevelDebug || config.OutputFormat != OutputFormatTEXT || config.LogFilePath != path || config.AddSource {
+		t.Errorf("unexpected options %+v", config)
+	}
+	if config.NameLevels["noisy"] != LevelError {
+		t.Errorf("unexpected name levels %v", config.NameLevels)
+	}
+
+	logger, handle, err := Build(WithEnv("APP"), WithSetDefault(false))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	logger.Debug("debug message")
+	WithName(logger, "noisy").Warn("dropped")
+	handle.Close()
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "DBG debug message") || strings.Contains(string(data), "dropped") {
+		t.Errorf("wrong logs output data %q", data)
+	}
+}
+
+func TestWithEnvUnset(t *testing.T) {
+	os.Unsetenv("GLOG_TEST_LEVEL")
+	logger, _, err := Build(WithEnv("GLOG_TEST"), WithLevel("warn"), WithSetDefault(false))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if logger.Enabled(context.Background(), LevelInfo) {
+		t.Error("expected options to stay unchanged when variables are not set")
+	}
+}
+
+func TestWithEnvInvalid(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value string
+	}{
+		{"LEVEL", "verbose"},
+		{"LEVELS", "noisy"},
+		{"FORMAT", "xml"},
+		{"ADD_SOURCE", "maybe"},
+		{"MAX_SIZE", "big"},
+		{"MAX_AGE", "week"},
+		{"MAX_BACKUPS", "three"},
+		{"COMPRESS", "yes please"},
+		{"LOCAL_TIME", "local"},
+		{"REOPEN_ON_SIGHUP", "on signal"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Setenv("APP_"+testCase.name, testCase.value)
+			_, _, err := Build(WithEnv("APP"), WithSetDefault(false))
+			if err == nil {
+				t.Fatal("expected error, got no error")
+			}
+			if !strings.Contains(err.Error(), "APP_"+testCase.name) {
+				t.Errorf("expected error to mention the variable, got %s", err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,447 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	gelfVersion = "1.1"
+	// gelfChunkSize is the maximum UDP datagram size, it fits into the common MTU with IP and UDP headers
+	gelfChunkSize      = 1420
+	gelfChunkHeaderLen = 12
+	gelfMaxChunks      = 128
+
+	gelfDialTimeout  = 5 * time.Second
+	gelfWriteTimeout = 10 * time.Second
+	gelfMinBackoff   = 100 * time.Millisecond
+	gelfMaxBackoff   = 5 * time.Second
+)
+
+var (
+	gelfChunkMagic     = []byte{0x1e, 0x0f}
+	errGELFUnavailable = errors.New("GELF input is unavailable, message dropped")
+)
+
+// GELFHandler writes records as GELF 1.1 JSON messages, one message per Write call ended by a newline.
+// Attributes are written as additional fields prefixed with '_', attributes of groups are flattened with dots.
+// ReplaceAttr of opts is applied to the time, level, message and source before they are written as GELF
+// fields, replaced values of other types than the original ones are ignored.
+type GELFHandler struct {
+	opts   HandlerOptions
+	host   string
+	attrs  []Attr
+	groups []string
+	group  string
+
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+// NewGELFHandler creates GELF handler, the host field is set to the host name
+func NewGELFHandler(w io.Writer, opts *HandlerOptions) *GELFHandler {
+	h := &GELFHandler{w: w, mu: &sync.Mutex{}}
+	if opts != nil {
+		h.opts = *opts
+	}
+	h.host, _ = os.Hostname()
+	if h.host == "" {
+		h.host = "localhost"
+	}
+	return h
+}
+
+func (h *GELFHandler) Enabled(_ context.Context, level Level) bool {
+	minLevel := LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+func (h *GELFHandler) Handle(_ context.Context, r Record) error {
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	timeValue, hasTime := h.replaceBuiltin(slog.Time(TimeKey, t))
+	levelValue, hasLevel := h.replaceBuiltin(slog.Any(LevelKey, r.Level))
+	messageValue, _ := h.replaceBuiltin(StringAttr(MessageKey, r.Message))
+
+	message := messageValue.String()
+	short, _, _ := strings.Cut(message, "\n")
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"version":"` + gelfVersion + `","host":`)
+	appendJSONString(&buf, h.host)
+	buf.WriteString(`,"short_message":`)
+	appendJSONString(&buf, short)
+	if short != message {
+		buf.WriteString(`,"full_message":`)
+		appendJSONString(&buf, message)
+	}
+	if t, ok := timeValue.Any().(time.Time); hasTime && ok {
+		buf.WriteString(`,"timestamp":`)
+		buf.WriteString(strconv.FormatFloat(float64(t.UnixMilli())/1e3, 'f', 3, 64))
+	}
+	if level, ok := levelValue.Any().(Level); hasLevel && ok {
+		buf.WriteString(`,"level":`)
+		buf.WriteString(strconv.Itoa(syslogSeverity(level)))
+	}
+
+	fields := make(map[string]struct{})
+	if h.opts.AddSource && r.PC != 0 {
+		sourceValue, ok := h.replaceBuiltin(slog.Any(SourceKey, recordSource(r)))
+		if src, isSource := sourceValue.Any().(*Source); ok && isSource {
+			h.appendResolved(&buf, fields, "", StringAttr("_file", src.File))
+			h.appendResolved(&buf, fields, "", IntAttr("_line", src.Line))
+			h.appendResolved(&buf, fields, "", StringAttr("_function", src.Function))
+		}
+	}
+	for _, attr := range h.attrs {
+		h.appendField(&buf, fields, "", nil, attr)
+	}
+	r.Attrs(func(attr Attr) bool {
+		h.appendField(&buf, fields, h.group, h.groups, attr)
+		return true
+	})
+	buf.WriteString("}\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf.Bytes())
+
+	return err
+}
+
+func (h *GELFHandler) WithAttrs(attrs []Attr) Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.attrs = append([]Attr(nil), h.attrs...)
+	for _, attr := range attrs {
+		for i := len(h.groups) - 1; i >= 0; i-- {
+			attr = Attr{Key: h.groups[i], Value: GroupValue(attr)}
+		}
+		h2.attrs = append(h2.attrs, attr)
+	}
+	return &h2
+}
+
+func (h *GELFHandler) WithGroup(name string) Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	h2.group = h.group + name + "."
+	return &h2
+}
+
+// replaceBuiltin applies ReplaceAttr to the builtin attribute, it returns false if the attribute is removed
+func (h *GELFHandler) replaceBuiltin(attr Attr) (Value, bool) {
+	rep := h.opts.ReplaceAttr
+	if rep == nil {
+		return attr.Value, true
+	}
+	replaced := rep(nil, attr)
+	replaced.Value = replaced.Value.Resolve()
+	if replaced.Equal(Attr{}) {
+		return Value{}, false
+	}
+	return replaced.Value, true
+}
+
+// appendField appends attribute as additional field applying ReplaceAttr, members of groups are flattened
+func (h *GELFHandler) appendField(buf *bytes.Buffer, fields map[string]struct{}, prefix string, groups []string, attr Attr) {
+	attr.Value = attr.Value.Resolve()
+	if rep := h.opts.ReplaceAttr; rep != nil && attr.Value.Kind() != KindGroup {
+		attr = rep(groups, attr)
+		attr.Value = attr.Value.Resolve()
+	}
+	if attr.Equal(Attr{}) {
+		return
+	}
+
+	if attr.Value.Kind() == KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+			groups = append(groups[:len(groups):len(groups)], attr.Key)
+		}
+		for _, member := range attr.Value.Group() {
+			h.appendField(buf, fields, prefix, groups, member)
+		}
+		return
+	}
+
+	h.appendResolved(buf, fields, prefix, attr)
+}
+
+// appendResolved appends attribute with resolved value, a field already written is not repeated
+func (h *GELFHandler) appendResolved(buf *bytes.Buffer, fields map[string]struct{}, prefix string, attr Attr) {
+	key := gelfFieldName(prefix + attr.Key)
+	if _, ok := fields[key]; ok {
+		return
+	}
+	fields[key] = struct{}{}
+
+	buf.WriteByte(',')
+	appendJSONString(buf, key)
+	buf.WriteByte(':')
+	appendGELFValue(buf, attr.Value)
+}
+
+// gelfFieldName returns additional field name with the '_' prefix and characters allowed by GELF,
+// the reserved "_id" field is renamed
+func gelfFieldName(key string) string {
+	if !strings.HasPrefix(key, "_") {
+		key = "_" + key
+	}
+	if key == "_id" {
+		key = "_id_"
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+}
+
+// appendGELFValue appends number values as JSON numbers and all other values as strings
+func appendGELFValue(buf *bytes.Buffer, v Value) {
+	switch v.Kind() {
+	case KindInt64:
+		buf.WriteString(strconv.FormatInt(v.Int64(), 10))
+	case KindUint64:
+		buf.WriteString(strconv.FormatUint(v.Uint64(), 10))
+	case KindFloat64:
+		f := v.Float64()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			appendJSONString(buf, strconv.FormatFloat(f, 'g', -1, 64))
+			return
+		}
+		buf.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+	case KindTime:
+		appendJSONString(buf, v.Time().Format(time.RFC3339Nano))
+	case KindAny:
+		switch x := v.Any().(type) {
+		case error:
+			appendJSONString(buf, x.Error())
+		case encoding.TextMarshaler:
+			data, err := x.MarshalText()
+			if err != nil {
+				appendJSONString(buf, "!ERROR:"+err.Error())
+				return
+			}
+			appendJSONString(buf, string(data))
+		default:
+			appendJSONString(buf, v.String())
+		}
+	default:
+		appendJSONString(buf, v.String())
+	}
+}
+
+func appendJSONString(buf *bytes.Buffer, s string) {
+	data, _ := json.Marshal(s)
+	buf.Write(data)
+}
+
+// GELFWriter sends GELF messages written by GELFHandler to a Graylog input. Messages are sent over UDP
+// in chunks if they don't fit into one datagram, or over TCP delimited by a null byte. A broken TCP
+// connection is reestablished in the background, messages written meanwhile are dropped.
+type GELFWriter struct {
+	network string
+	address string
+	// stop cancels the background reconnection on Close
+	stop   context.Context
+	cancel context.CancelFunc
+
+	mu           sync.Mutex
+	conn         net.Conn
+	reconnecting bool
+	closed       bool
+	done         sync.WaitGroup
+}
+
+// NewGELFWriter connects to the GELF input at address, network is "udp" or "tcp"
+func NewGELFWriter(network, address string) (*GELFWriter, error) {
+	switch network {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("unsupported GELF network '%s'", network)
+	}
+
+	w := &GELFWriter{network: network, address: address}
+	w.stop, w.cancel = context.WithCancel(context.Background())
+	conn, err := w.dial()
+	if err != nil {
+		return nil, err
+	}
+	w.conn = conn
+	return w, nil
+}
+
+func (w *GELFWriter) dial() (net.Conn, error) {
+	dialer := net.Dialer{Timeout: gelfDialTimeout}
+	conn, err := dialer.DialContext(w.stop, w.network, w.address)
+	if err != nil {
+		return nil, fmt.Errorf("connect to GELF input: %w", err)
+	}
+	return conn, nil
+}
+
+func (w *GELFWriter) isTCP() bool {
+	return strings.HasPrefix(w.network, "tcp")
+}
+
+// Write sends one GELF message, the trailing newline is removed
+func (w *GELFWriter) Write(p []byte) (int, error) {
+	msg := bytes.TrimSuffix(p, []byte("\n"))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.conn == nil {
+		w.reconnect()
+		return 0, errGELFUnavailable
+	}
+
+	var err error
+	if w.isTCP() {
+		err = w.writeTCP(msg)
+	} else {
+		err = w.writeUDP(msg)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *GELFWriter) writeTCP(msg []byte) error {
+	frame := make([]byte, 0, len(msg)+1)
+	frame = append(append(frame, msg...), 0)
+
+	w.conn.SetWriteDeadline(time.Now().Add(gelfWriteTimeout))
+	if _, err := w.conn.Write(frame); err != nil {
+		// the connection may be closed by the server or the input is stuck, the rest of the frame
+		// would corrupt the stream of a new connection
+		w.conn.Close()
+		w.conn = nil
+		w.reconnect()
+		return err
+	}
+	return nil
+}
+
+// reconnect dials the input in the background with backoff until it is connected or the writer is closed,
+// it is called with the mutex held
+func (w *GELFWriter) reconnect() {
+	if w.reconnecting {
+		return
+	}
+	w.reconnecting = true
+	w.done.Add(1)
+
+	go func() {
+		defer w.done.Done()
+		backoff := gelfMinBackoff
+		for {
+			conn, err := w.dial()
+
+			w.mu.Lock()
+			if err == nil && !w.closed {
+				w.conn = conn
+			} else if err == nil {
+				conn.Close()
+			}
+			if err == nil || w.closed {
+				w.reconnecting = false
+				w.mu.Unlock()
+				return
+			}
+			w.mu.Unlock()
+
+			timer := time.NewTimer(backoff)
+			select {
+			case <-timer.C:
+			case <-w.stop.Done():
+				timer.Stop()
+			}
+			backoff = min(2*backoff, gelfMaxBackoff)
+		}
+	}()
+}
+
+func (w *GELFWriter) writeUDP(msg []byte) error {
+	if len(msg) <= gelfChunkSize {
+		_, err := w.conn.Write(msg)
+		return err
+	}
+
+	const dataSize = gelfChunkSize - gelfChunkHeaderLen
+	count := (len(msg) + dataSize - 1) / dataSize
+	if count > gelfMaxChunks {
+		return errors.New("GELF message is too large for UDP")
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+
+	chunk := make([]byte, 0, gelfChunkSize)
+	for i := 0; i < count; i++ {
+		end := min((i+1)*dataSize, len(msg))
+		chunk = append(chunk[:0], gelfChunkMagic...)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, msg[i*dataSize:end]...)
+		if _, err := w.conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the connection and stops the background reconnection
+func (w *GELFWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.cancel()
+	var err error
+	if w.conn != nil {
+		err = w.conn.Close()
+		w.conn = nil
+	}
+	w.mu.Unlock()
+
+	w.done.Wait()
+	return err
+}
@@ -0,0 +1,282 @@
+package glog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGELFHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(NewGELFHandler(&buf, &HandlerOptions{AddSource: true})).
+		With(StringAttr("service", "api"), StringAttr("id", "reserved")).
+		WithGroup("request")
+
+	logger.Error(
+		"request failed\nstack trace",
+		IntAttr("status", 502),
+		Float64Attr("duration", 0.5),
+		BoolAttr("retry", true),
+		ErrAttr(errors.New("upstream timeout")),
+		Group("client", StringAttr("ip", "10.0.0.1")),
+		StringAttr("bad key!", "v"),
+	)
+
+	if !bytes.HasSuffix(buf.Bytes(), []byte("}\n")) {
+		t.Fatalf("expected message ended by newline, got %q", buf.String())
+	}
+	m := decodeJSONLine(t, &buf)
+
+	host, _ := os.Hostname()
+	expected := map[string]any{
+		"version":            "1.1",
+		"host":               host,
+		"short_message":      "request failed",
+		"full_message":       "request failed\nstack trace",
+		"level":              float64(3),
+		"_service":           "api",
+		"_id_":               "reserved",
+		"_request.status":    float64(502),
+		"_request.duration":  0.5,
+		"_request.retry":     "true",
+		"_request.error":     "upstream timeout",
+		"_request.client.ip": "10.0.0.1",
+		"_request.bad_key_":  "v",
+		"_function":          "github.com/kda47/glog.TestGELFHandler",
+	}
+	for key, value := range expected {
+		if m[key] != value {
+			t.Errorf("expected %s=%v, got %v", key, value, m[key])
+		}
+	}
+	if ts, ok := m["timestamp"].(float64); !ok || time.Since(time.UnixMilli(int64(ts*1000))) > time.Minute {
+		t.Errorf("unexpected timestamp %v", m["timestamp"])
+	}
+	if file, ok := m["_file"].(string); !ok || !strings.HasSuffix(file, "gelf_test.go") {
+		t.Errorf("unexpected _file %v", m["_file"])
+	}
+	if _, ok := m["_line"].(float64); !ok {
+		t.Errorf("unexpected _line %v", m["_line"])
+	}
+
+	// ReplaceAttr is applied to builtin fields and attributes with their groups
+	replace := func(groups []string, a Attr) Attr {
+		switch {
+		case groups == nil && a.Key == MessageKey:
+			return StringAttr(a.Key, "["+a.Value.String()+"]")
+		case groups == nil && a.Key == TimeKey:
+			return Attr{}
+		case strings.Join(groups, ".") == "request.client" && a.Key == "ip":
+			return StringAttr("ip", "masked")
+		case a.Key == "secret":
+			return Attr{}
+		}
+		return a
+	}
+	New(NewGELFHandler(&buf, &HandlerOptions{ReplaceAttr: replace})).
+		WithGroup("request").With(Group("client", StringAttr("ip", "10.0.0.1"))).
+		Info("replaced", StringAttr("secret", "x"), Group("client", StringAttr("port", "80")))
+	m = decodeJSONLine(t, &buf)
+	if m["short_message"] != "[replaced]" || m["timestamp"] != nil || m["level"] != float64(6) ||
+		m["_request.client.ip"] != "masked" || m["_request.client.port"] != "80" || m["_request.secret"] != nil {
+		t.Errorf("unexpected replaced message %v", m)
+	}
+
+	h := NewGELFHandler(&buf, nil)
+	if h.WithGroup("") != h || h.WithAttrs(nil) != h {
+		t.Error("expected the same handler for empty group and attributes")
+	}
+}
+
+// reassembleGELF joins GELF UDP chunks, it expects chunks of one message in order
+func reassembleGELF(t *testing.T, conn net.PacketConn) []byte {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var msg []byte
+	packet := make([]byte, 65536)
+	for {
+		n, _, err := conn.ReadFrom(packet)
+		if err != nil {
+			t.Fatalf("read error: %s", err.Error())
+		}
+		data := packet[:n]
+		if !bytes.HasPrefix(data, gelfChunkMagic) {
+			return append(msg, data...)
+		}
+		if n > gelfChunkSize {
+			t.Fatalf("chunk exceeds max size: %d", n)
+		}
+		seq, count := int(data[10]), int(data[11])
+		msg = append(msg, data[gelfChunkHeaderLen:]...)
+		if seq == count-1 {
+			return msg
+		}
+	}
+}
+
+func TestGELFWriterUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %s", err.Error())
+	}
+	defer conn.Close()
+
+	logger, handle, err := Build(
+		WithOutputFormat(OutputFormatGELF),
+		WithOutputFilePath("udp://"+conn.LocalAddr().String()),
+		WithSetDefault(false),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	defer handle.Close()
+
+	logger.Info("small")
+	var m map[string]any
+	if err := json.Unmarshal(reassembleGELF(t, conn), &m); err != nil || m["short_message"] != "small" {
+		t.Errorf("unexpected message %v, err %v", m, err)
+	}
+
+	large := strings.Repeat("x", 5000)
+	logger.Info("large", StringAttr("payload", large))
+	m = nil
+	if err := json.Unmarshal(reassembleGELF(t, conn), &m); err != nil || m["_payload"] != large {
+		t.Errorf("unexpected chunked message, err %v", err)
+	}
+
+	w, _ := NewGELFWriter("udp", conn.LocalAddr().String())
+	if _, err := w.Write(bytes.Repeat([]byte("x"), gelfMaxChunks*gelfChunkSize)); err == nil {
+		t.Error("expected error for too large message")
+	}
+	w.Close()
+	if _, err := w.Write([]byte("{}")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("expected os.ErrClosed, got %v", err)
+	}
+}
+
+func TestGELFWriterTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %s", err.Error())
+	}
+	defer ln.Close()
+
+	messages := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					msg, err := r.ReadString(0)
+					if err != nil {
+						return
+					}
+					messages <- strings.TrimSuffix(msg, "\x00")
+				}
+			}()
+		}
+	}()
+
+	w, err := NewGELFWriter("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	defer w.Close()
+	logger := New(NewGELFHandler(w, nil))
+
+	logger.Info("first")
+	logger.Warn("second")
+	for _, expected := range []string{"first", "second"} {
+		select {
+		case msg := <-messages:
+			var m map[string]any
+			if err := json.Unmarshal([]byte(msg), &m); err != nil || m["short_message"] != expected {
+				t.Errorf("unexpected message %q, err %v", msg, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for message")
+		}
+	}
+
+	if _, err := NewGELFWriter("unix", "/tmp/gelf"); err == nil {
+		t.Error("expected error for unsupported network")
+	}
+	if _, _, err := Build(WithOutputFilePath("tcp://"+ln.Addr().String()), WithSetDefault(false)); err == nil {
+		t.Error("expected error for network destination with JSON format")
+	}
+}
+
+func TestGELFWriterTCPReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %s", err.Error())
+	}
+	defer ln.Close()
+
+	conns := make(chan net.Conn, 10)
+	messages := make(chan string, 100)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+			go func() {
+				r := bufio.NewReader(conn)
+				for {
+					msg, err := r.ReadString(0)
+					if err != nil {
+						return
+					}
+					messages <- strings.TrimSuffix(msg, "\x00")
+				}
+			}()
+		}
+	}()
+
+	w, err := NewGELFWriter("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	defer w.Close()
+
+	// the connection closed by the server is reestablished in the background, writes don't wait for it
+	(<-conns).Close()
+	deadline := time.Now().Add(10 * time.Second)
+	for received := false; !received; {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for reconnection")
+		}
+		start := time.Now()
+		w.Write([]byte(`{"short_message":"after reconnect"}`))
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("write blocked for %s", elapsed)
+		}
+		select {
+		case msg := <-messages:
+			received = strings.Contains(msg, "after reconnect")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	if len(conns) != 1 {
+		t.Errorf("expected one new connection, got %d", len(conns))
+	}
+
+	w.Close()
+	if _, err := w.Write([]byte("{}")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("expected os.ErrClosed, got %v", err)
+	}
+}
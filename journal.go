@@ -0,0 +1,236 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JournalSocket is the socket of the systemd journal native protocol
+const JournalSocket = "/run/systemd/journal/socket"
+
+// JournalHandler writes records as entries of the systemd journal native protocol, one entry per Write call.
+// Attributes are written as uppercase journal fields, attributes of groups are flattened with underscores,
+// e.g. REQUEST_METHOD. The level is written as PRIORITY and the source as CODE_FILE, CODE_LINE and CODE_FUNC.
+// ReplaceAttr of opts is applied to the attributes before their keys are converted to field names.
+type JournalHandler struct {
+	opts       HandlerOptions
+	identifier string
+	attrs      []Attr
+	groups     []string
+	group      string
+
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+// NewJournalHandler creates journal handler, SYSLOG_IDENTIFIER of entries is the program name
+func NewJournalHandler(w io.Writer, opts *HandlerOptions) *JournalHandler {
+	h := &JournalHandler{w: w, identifier: filepath.Base(os.Args[0]), mu: &sync.Mutex{}}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+func (h *JournalHandler) Enabled(_ context.Context, level Level) bool {
+	minLevel := LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+func (h *JournalHandler) Handle(_ context.Context, r Record) error {
+	var buf bytes.Buffer
+	appendJournalField(&buf, "MESSAGE", r.Message)
+	appendJournalField(&buf, "PRIORITY", strconv.Itoa(syslogSeverity(r.Level)))
+	appendJournalField(&buf, "SYSLOG_IDENTIFIER", h.identifier)
+	if h.opts.AddSource && r.PC != 0 {
+		src := recordSource(r)
+		appendJournalField(&buf, "CODE_FILE", src.File)
+		appendJournalField(&buf, "CODE_LINE", strconv.Itoa(src.Line))
+		appendJournalField(&buf, "CODE_FUNC", src.Function)
+	}
+	field := func(key string, v Value) {
+		appendJournalField(&buf, journalFieldName(key), syslogValueString(v))
+	}
+	for _, attr := range h.attrs {
+		syslogAttrFunc("", nil, attr, h.opts.ReplaceAttr, field)
+	}
+	r.Attrs(func(attr Attr) bool {
+		syslogAttrFunc(h.group, h.groups, attr, h.opts.ReplaceAttr, field)
+		return true
+	})
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf.Bytes())
+
+	return err
+}
+
+func (h *JournalHandler) WithAttrs(attrs []Attr) Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.attrs = append([]Attr(nil), h.attrs...)
+	for _, attr := range attrs {
+		for i := len(h.groups) - 1; i >= 0; i-- {
+			attr = Attr{Key: h.groups[i], Value: GroupValue(attr)}
+		}
+		h2.attrs = append(h2.attrs, attr)
+	}
+	return &h2
+}
+
+func (h *JournalHandler) WithGroup(name string) Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	h2.group = h.group + name + "."
+	return &h2
+}
+
+// appendJournalField appends field in the text form KEY=value or, if the value contains a newline,
+// in the binary form with the value length as a little endian 64 bit integer
+func appendJournalField(buf *bytes.Buffer, key, value string) {
+	buf.WriteString(key)
+	if strings.ContainsRune(value, '\n') {
+		buf.WriteByte('\n')
+		buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(value))))
+	} else {
+		buf.WriteByte('=')
+	}
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// journalHandlerFields are written by JournalHandler, attributes with these names are prefixed with ATTR_
+var journalHandlerFields = map[string]struct{}{
+	"MESSAGE":           {},
+	"PRIORITY":          {},
+	"SYSLOG_IDENTIFIER": {},
+	"CODE_FILE":         {},
+	"CODE_LINE":         {},
+	"CODE_FUNC":         {},
+}
+
+// journalFieldName returns field name of at most 64 uppercase letters, digits and underscores which
+// starts with a letter, names starting with an underscore are reserved for trusted fields of the journal
+// and names of fields written by the handler are prefixed with ATTR_
+func journalFieldName(key string) string {
+	key = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		default:
+			return '_'
+		}
+	}, key)
+	key = strings.TrimLeft(key, "_0123456789")
+	if key == "" {
+		return "FIELD"
+	}
+	if _, ok := journalHandlerFields[key]; ok {
+		return "ATTR_" + key
+	}
+	return key[:min(len(key), 64)]
+}
+
+// JournalWriter sends entries written by JournalHandler to the systemd journal, one datagram per entry.
+// Entries too large for a datagram are passed in a file descriptor like by sd_journal_send. The socket
+// is reconnected if the journal was restarted.
+type JournalWriter struct {
+	path string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	closed bool
+}
+
+// NewJournalWriter connects to the journal socket at path, JournalSocket if path is empty
+func NewJournalWriter(path string) (*JournalWriter, error) {
+	if path == "" {
+		path = JournalSocket
+	}
+
+	w := &JournalWriter{path: path}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *JournalWriter) connect() error {
+	conn, err := net.Dial("unixgram", w.path)
+	if err != nil {
+		return fmt.Errorf("connect to journal: %w", err)
+	}
+	w.conn = conn
+	return nil
+}
+
+// Write sends one journal entry
+func (w *JournalWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.conn == nil {
+		if err := w.connect(); err != nil {
+			return 0, err
+		}
+	}
+
+	_, err := w.conn.Write(p)
+	if isMessageTooLarge(err) {
+		if err := sendJournalFile(w.conn, p); err != nil {
+			return 0, fmt.Errorf("send large journal entry: %w", err)
+		}
+		return len(p), nil
+	}
+	if err != nil {
+		// the socket is recreated when the journal is restarted, reconnect and retry once
+		w.conn.Close()
+		w.conn = nil
+		if err := w.connect(); err != nil {
+			return 0, err
+		}
+		if _, err := w.conn.Write(p); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *JournalWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package glog
+
+import (
+	"errors"
+	"net"
+)
+
+func isMessageTooLarge(_ error) bool {
+	return false
+}
+
+func sendJournalFile(_ net.Conn, _ []byte) error {
+	return errors.ErrUnsupported
+}
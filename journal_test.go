@@ -0,0 +1,175 @@
+package glog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parseJournalEntry parses entry of the journal native protocol, values of repeated fields are joined with '|'
+func parseJournalEntry(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	fields := make(map[string]string)
+	for len(data) > 0 {
+		i := bytes.IndexAny(data, "=\n")
+		if i < 0 {
+			t.Fatalf("invalid entry %q", data)
+		}
+		key := string(data[:i])
+		var value string
+		if data[i] == '=' {
+			end := bytes.IndexByte(data[i:], '\n')
+			value = string(data[i+1 : i+end])
+			data = data[i+end+1:]
+		} else {
+			size := int(binary.LittleEndian.Uint64(data[i+1 : i+9]))
+			value = string(data[i+9 : i+9+size])
+			if data[i+9+size] != '\n' {
+				t.Fatalf("invalid binary field %s", key)
+			}
+			data = data[i+9+size+1:]
+		}
+		if prev, ok := fields[key]; ok {
+			value = prev + "|" + value
+		}
+		fields[key] = value
+	}
+	return fields
+}
+
+func TestJournalHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(NewJournalHandler(&buf, &HandlerOptions{AddSource: true, Level: LevelDebug})).
+		With(StringAttr(NameKey, "worker")).
+		WithGroup("request")
+
+	logger.Warn("request failed\nretrying", StringAttr("method", "GET"), IntAttr("status", 502),
+		Group("client", StringAttr("ip", "10.0.0.1")), StringAttr("_trusted", "no"), StringAttr("1x", "y"))
+
+	fields := parseJournalEntry(t, buf.Bytes())
+	expected := map[string]string{
+		"MESSAGE":           "request failed\nretrying",
+		"PRIORITY":          "4",
+		"SYSLOG_IDENTIFIER": filepath.Base(os.Args[0]),
+		"CODE_FUNC":         "github.com/kda47/glog.TestJournalHandler",
+		"NAME":              "worker",
+		"REQUEST_METHOD":    "GET",
+		"REQUEST_STATUS":    "502",
+		"REQUEST_CLIENT_IP": "10.0.0.1",
+		"REQUEST__TRUSTED":  "no",
+		"REQUEST_1X":        "y",
+	}
+	for key, value := range expected {
+		if fields[key] != value {
+			t.Errorf("expected %s=%q, got %q", key, value, fields[key])
+		}
+	}
+	if !strings.HasSuffix(fields["CODE_FILE"], "journal_test.go") || fields["CODE_LINE"] == "" {
+		t.Errorf("unexpected source fields %v", fields)
+	}
+	buf.Reset()
+
+	New(NewJournalHandler(&buf, &HandlerOptions{Level: LevelDebug})).Debug("debug", StringAttr("_", "v"))
+	fields = parseJournalEntry(t, buf.Bytes())
+	if fields["PRIORITY"] != "7" || fields["FIELD"] != "v" || fields["CODE_FILE"] != "" {
+		t.Errorf("unexpected entry %v", fields)
+	}
+	buf.Reset()
+
+	// attributes do not duplicate fields written by the handler
+	New(NewJournalHandler(&buf, nil)).Info("done", StringAttr("message", "attr"), IntAttr("priority", 1),
+		StringAttr("code_line", "10"))
+	fields = parseJournalEntry(t, buf.Bytes())
+	if fields["MESSAGE"] != "done" || fields["PRIORITY"] != "6" || fields["CODE_LINE"] != "" ||
+		fields["ATTR_MESSAGE"] != "attr" || fields["ATTR_PRIORITY"] != "1" || fields["ATTR_CODE_LINE"] != "10" {
+		t.Errorf("unexpected entry %v", fields)
+	}
+	buf.Reset()
+
+	// ReplaceAttr gets the original keys
+	replace := func(groups []string, a Attr) Attr {
+		if a.Key == "token" {
+			return Attr{}
+		}
+		if len(groups) == 1 && groups[0] == "request" && a.Key == "method" {
+			return StringAttr("verb", a.Value.String())
+		}
+		return a
+	}
+	New(NewJournalHandler(&buf, &HandlerOptions{ReplaceAttr: replace})).With(StringAttr("token", "secret")).
+		WithGroup("request").Info("replaced", StringAttr("method", "GET"), StringAttr("TOKEN", "kept"))
+	fields = parseJournalEntry(t, buf.Bytes())
+	if fields["REQUEST_VERB"] != "GET" || fields["REQUEST_METHOD"] != "" || fields["TOKEN"] != "" || fields["REQUEST_TOKEN"] != "kept" {
+		t.Errorf("unexpected entry with replaced attributes %v", fields)
+	}
+}
+
+func TestJournalWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Skipf("unixgram is not supported: %s", err.Error())
+	}
+	defer conn.Close()
+
+	logger, handle, err := Build(
+		WithOutputFormat(OutputFormatJournal),
+		WithOutputFilePath("unixgram://"+path),
+		WithSetDefault(false),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	defer handle.Close()
+
+	logger.Error("failed", StringAttr("key", "value"))
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	packet := make([]byte, 65536)
+	n, _, err := conn.ReadFrom(packet)
+	if err != nil {
+		t.Fatalf("read error: %s", err.Error())
+	}
+	fields := parseJournalEntry(t, packet[:n])
+	if fields["MESSAGE"] != "failed" || fields["PRIORITY"] != "3" || fields["KEY"] != "value" {
+		t.Errorf("unexpected entry %v", fields)
+	}
+
+	// the journal is restarted and the socket is recreated
+	conn.Close()
+	os.Remove(path)
+	conn, err = net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Fatalf("listen error: %s", err.Error())
+	}
+	defer conn.Close()
+
+	logger.Info("after restart")
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err = conn.ReadFrom(packet)
+	if err != nil {
+		t.Fatalf("read error: %s", err.Error())
+	}
+	if fields := parseJournalEntry(t, packet[:n]); fields["MESSAGE"] != "after restart" {
+		t.Errorf("unexpected entry %v", fields)
+	}
+
+	handle.Close()
+	if _, err := NewJournalWriter(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected error for missing socket")
+	}
+	if f, err := ParseOutputFormat("journal"); err != nil || f != OutputFormatJournal {
+		t.Errorf("unexpected format %s, err %v", f, err)
+	}
+	w, _ := NewJournalWriter(path)
+	w.Close()
+	if _, err := w.Write([]byte("MESSAGE=x\n")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("expected os.ErrClosed, got %v", err)
+	}
+}
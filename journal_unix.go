@@ -0,0 +1,56 @@
+//go:build unix
+
+package glog
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+// journalFileDir is the directory of files with large entries, the journal accepts files of /dev/shm,
+// /tmp and /var/tmp
+const journalFileDir = "/dev/shm"
+
+func isMessageTooLarge(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)
+}
+
+// sendJournalFile writes the entry to an unlinked file and sends its descriptor to the journal
+func sendJournalFile(conn net.Conn, p []byte) error {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return errors.New("journal connection is not a unix socket")
+	}
+
+	dir := journalFileDir
+	if _, err := os.Stat(dir); err != nil {
+		dir = os.TempDir()
+	}
+	f, err := os.CreateTemp(dir, "glog-journal-")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		return err
+	}
+	if _, err := f.Write(p); err != nil {
+		return err
+	}
+
+	// WriteMsgUnix does not accept connected datagram sockets
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return err
+	}
+	rights := syscall.UnixRights(int(f.Fd()))
+	if werr := raw.Write(func(fd uintptr) bool {
+		err = syscall.Sendmsg(int(fd), nil, rights, nil, 0)
+		return err != syscall.EAGAIN
+	}); werr != nil {
+		return werr
+	}
+	return err
+}
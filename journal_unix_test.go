@@ -0,0 +1,62 @@
+//go:build unix
+
+package glog
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestJournalWriterLargeEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram is not supported: %s", err.Error())
+	}
+	defer conn.Close()
+
+	w, err := NewJournalWriter(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	defer w.Close()
+
+	var buf strings.Builder
+	New(NewJournalHandler(&buf, nil)).Info(strings.Repeat("x", 4<<20))
+	if n, err := w.Write([]byte(buf.String())); err != nil || n != buf.Len() {
+		t.Fatalf("expected %d bytes written, got %d, err %v", buf.Len(), n, err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	oob := make([]byte, syscall.CmsgSpace(4))
+	n, oobn, _, _, err := conn.ReadMsgUnix(make([]byte, 1), oob)
+	if err != nil {
+		t.Fatalf("read error: %s", err.Error())
+	}
+	if n != 0 {
+		t.Fatalf("expected entry to be sent in a file descriptor, got %d bytes", n)
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("unexpected control messages %v, err %v", msgs, err)
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("unexpected rights %v, err %v", fds, err)
+	}
+	f := os.NewFile(uintptr(fds[0]), "entry")
+	defer f.Close()
+	data, err := io.ReadAll(io.NewSectionReader(f, 0, 1<<30))
+	if err != nil {
+		t.Fatalf("read error: %s", err.Error())
+	}
+	if fields := parseJournalEntry(t, data); len(fields["MESSAGE"]) != 4<<20 {
+		t.Errorf("unexpected entry size %d", len(data))
+	}
+}
@@ -0,0 +1,99 @@
+package glog
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// LevelController holds the level of a logger which can be changed at runtime.
+// Loggers derived with With, WithGroup or stored in contexts follow the change atomically.
+type LevelController struct {
+	v       slog.LevelVar
+	initial Level
+
+	mu          sync.Mutex
+	revert      *time.Timer
+	revertLevel Level
+	expireAt    time.Time
+}
+
+// NewLevelController creates level controller with the initial level
+func NewLevelController(level Level) *LevelController {
+	c := &LevelController{initial: level}
+	c.v.Set(level)
+	return c
+}
+
+// Level returns the current level, it implements Leveler
+func (c *LevelController) Level() Level {
+	return c.v.Level()
+}
+
+// SetLevel changes the current level
+func (c *LevelController) SetLevel(level Level) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stopRevert()
+	c.v.Set(level)
+}
+
+// SetLevelFor changes the current level for ttl, after that the previous level is restored.
+// The revert is canceled by subsequent level changes.
+func (c *LevelController) SetLevelFor(level Level, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous := c.v.Level()
+	if c.revert != nil {
+		previous = c.revertLevel
+	}
+	c.stopRevert()
+	c.v.Set(level)
+
+	var timer *time.Timer
+	timer = time.AfterFunc(ttl, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.revert != timer {
+			return
+		}
+		c.revert = nil
+		c.expireAt = time.Time{}
+		c.v.Set(previous)
+	})
+	c.revert = timer
+	c.revertLevel = previous
+	c.expireAt = time.Now().Add(ttl)
+}
+
+// Expiration returns time when the level set with SetLevelFor is reverted, zero if there is no pending revert
+func (c *LevelController) Expiration() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.expireAt
+}
+
+// Reset restores the initial level
+func (c *LevelController) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stopRevert()
+	c.v.Set(c.initial)
+}
+
+func (c *LevelController) stopRevert() {
+	if c.revert != nil {
+		c.revert.Stop()
+		c.revert = nil
+		c.expireAt = time.Time{}
+	}
+}
+
+func (c *LevelController) String() string {
+	return c.v.String()
+}
@@ -0,0 +1,68 @@
+package glog
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLevelController(t *testing.T) {
+	c := NewLevelController(LevelWarn)
+	if c.Level() != LevelWarn {
+		t.Errorf("expected WARN level, got %s", c.Level())
+	}
+
+	c.SetLevel(LevelDebug)
+	if c.Level() != LevelDebug {
+		t.Errorf("expected DEBUG level, got %s", c.Level())
+	}
+	if c.String() != "LevelVar(DEBUG)" {
+		t.Errorf("unexpected string %q", c.String())
+	}
+
+	c.Reset()
+	if c.Level() != LevelWarn {
+		t.Errorf("expected WARN level after reset, got %s", c.Level())
+	}
+}
+
+func TestBuildLevelController(t *testing.T) {
+	ctx := context.Background()
+
+	for _, format := range []OutputFormat{OutputFormatJSON, OutputFormatTEXT} {
+		t.Run(format.String(), func(t *testing.T) {
+			logger, handle, err := Build(WithLevel("info"), WithOutputFormat(format), WithSetDefault(false))
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err.Error())
+			}
+			derived := WithName(logger, "derived").WithGroup("group")
+			ctxLogger := L(ContextWithLogger(ctx, derived))
+
+			if ctxLogger.Enabled(ctx, LevelDebug) {
+				t.Error("expected logger to be disabled for debug level")
+			}
+
+			handle.LevelController().SetLevel(LevelDebug)
+			for _, l := range []*Logger{logger, derived, ctxLogger} {
+				if !l.Enabled(ctx, LevelDebug) {
+					t.Error("expected logger to be enabled for debug level")
+				}
+			}
+
+			handle.LevelController().Reset()
+			if derived.Enabled(ctx, LevelDebug) {
+				t.Error("expected logger to be disabled for debug level after reset")
+			}
+		})
+	}
+
+	shared := NewLevelController(LevelError)
+	first, _, _ := Build(WithLevelController(shared), WithLevel("debug"), WithSetDefault(false))
+	second, _, _ := Build(WithLevelController(shared), WithSetDefault(false))
+	if first.Enabled(ctx, LevelWarn) || second.Enabled(ctx, LevelWarn) {
+		t.Error("expected loggers to be disabled for warn level")
+	}
+	shared.SetLevel(LevelWarn)
+	if !first.Enabled(ctx, LevelWarn) || !second.Enabled(ctx, LevelWarn) {
+		t.Error("expected loggers to be enabled for warn level")
+	}
+}
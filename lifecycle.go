@@ -0,0 +1,162 @@
+package glog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+type flusher interface {
+	Flush(ctx context.Context) error
+}
+
+type syncer interface {
+	Sync() error
+}
+
+// contextCloser is a sink which stops waiting for queued records when ctx is done
+type contextCloser interface {
+	closeContext(ctx context.Context) error
+}
+
+// Handle controls resources of a logger created by Build, e.g. opened log files.
+// Handles owning resources are registered until closed, so Shutdown can reach them.
+type Handle struct {
+	levels     *LevelController
+	nameLevels *NameLevels
+	rateLimits *RateLimits
+
+	mu     sync.Mutex
+	sinks  []io.Closer
+	closed bool
+}
+
+var handles = struct {
+	mu  sync.Mutex
+	set map[*Handle]struct{}
+}{set: make(map[*Handle]struct{})}
+
+func newHandle() *Handle {
+	return &Handle{}
+}
+
+// LevelController returns controller of the logger level, it doesn't affect loggers with a custom handler.
+// Sinks added with WithSink keep their own levels, the controller starts at the lowest of them.
+func (h *Handle) LevelController() *LevelController {
+	return h.levels
+}
+
+// NameLevels returns per-name levels of the logger which can be changed at runtime,
+// it is nil for loggers with a custom handler
+func (h *Handle) NameLevels() *NameLevels {
+	return h.nameLevels
+}
+
+// RateLimits returns rate limits of the logger which can be changed at runtime,
+// it is nil for loggers with a custom handler
+func (h *Handle) RateLimits() *RateLimits {
+	return h.rateLimits
+}
+
+// add appends sink to the handle, sinks are flushed and closed in reverse order
+func (h *Handle) add(sink io.Closer) {
+	if sink == nil {
+		return
+	}
+
+	h.mu.Lock()
+	h.sinks = append(h.sinks, sink)
+	h.mu.Unlock()
+
+	handles.mu.Lock()
+	handles.set[h] = struct{}{}
+	handles.mu.Unlock()
+}
+
+// Flush writes buffered records of the logger sinks and commits opened files to stable storage
+func (h *Handle) Flush(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil
+	}
+
+	return flushSinks(ctx, h.sinks)
+}
+
+// Close flushes and closes the logger sinks, records logged after Close are lost
+func (h *Handle) Close() error {
+	return h.close(context.Background())
+}
+
+// close is Close which stops waiting for queued records when ctx is done, the sinks are closed anyway
+func (h *Handle) close(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil
+	}
+	h.closed = true
+
+	handles.mu.Lock()
+	delete(handles.set, h)
+	handles.mu.Unlock()
+
+	errs := []error{flushSinks(ctx, h.sinks)}
+	for i := len(h.sinks) - 1; i >= 0; i-- {
+		if s, ok := h.sinks[i].(contextCloser); ok {
+			errs = append(errs, s.closeContext(ctx))
+		} else {
+			errs = append(errs, h.sinks[i].Close())
+		}
+	}
+	h.sinks = nil
+
+	return errors.Join(errs...)
+}
+
+func flushSinks(ctx context.Context, sinks []io.Closer) error {
+	var errs []error
+	for i := len(sinks) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		switch s := sinks[i].(type) {
+		case flusher:
+			errs = append(errs, s.Flush(ctx))
+		case syncer:
+			errs = append(errs, s.Sync())
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Shutdown flushes and closes sinks of all loggers created by glog which are not closed yet,
+// call it before the program exits so the last records are not lost. When ctx is done, queued records
+// not written yet are dropped, the sinks are closed and ctx.Err() is returned.
+func Shutdown(ctx context.Context) error {
+	handles.mu.Lock()
+	list := make([]*Handle, 0, len(handles.set))
+	for h := range handles.set {
+		list = append(list, h)
+	}
+	handles.mu.Unlock()
+
+	var errs []error
+	for _, h := range list {
+		errs = append(errs, h.Flush(ctx))
+	}
+	for _, h := range list {
+		errs = append(errs, h.close(ctx))
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return errors.Join(errs...)
+}
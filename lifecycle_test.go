@@ -0,0 +1,141 @@
+package glog
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testSink struct {
+	name   string
+	events *[]string
+	err    error
+}
+
+func (s *testSink) Flush(_ context.Context) error {
+	*s.events = append(*s.events, "flush "+s.name)
+	return s.err
+}
+
+func (s *testSink) Close() error {
+	*s.events = append(*s.events, "close "+s.name)
+	return nil
+}
+
+func TestHandle(t *testing.T) {
+	var events []string
+	h := newHandle()
+	h.add(nil)
+	h.add(&testSink{name: "file", events: &events})
+	h.add(&testSink{name: "async", events: &events})
+
+	if err := h.Flush(context.Background()); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+	if err := h.Close(); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+	if err := h.Close(); err != nil {
+		t.Errorf("expected no error on second close, got %s", err.Error())
+	}
+	if err := h.Flush(context.Background()); err != nil {
+		t.Errorf("expected no error on flush after close, got %s", err.Error())
+	}
+
+	expected := "flush async,flush file,flush async,flush file,close async,close file"
+	if got := strings.Join(events, ","); got != expected {
+		t.Errorf("expected events %q, got %q", expected, got)
+	}
+
+	events = events[:0]
+	sinkErr := errors.New("sink error")
+	h = newHandle()
+	h.add(&testSink{name: "file", events: &events, err: sinkErr})
+	if err := h.Flush(context.Background()); !errors.Is(err, sinkErr) {
+		t.Errorf("expected sink error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := h.Flush(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled error, got %v", err)
+	}
+	h.Close()
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	next := newGateHandler()
+	async := NewAsyncHandler(next, AsyncOptions{})
+	h := newHandle()
+	h.add(async)
+	logger := New(async)
+	logger.Info("blocked")
+	logger.Info("dropped")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+
+	close(next.gate)
+	<-async.q.done
+	if messages := next.Messages(); len(messages) != 1 || async.Dropped() != 1 {
+		t.Errorf("expected the queued record dropped after the deadline, got %v, dropped %d", messages, async.Dropped())
+	}
+}
+
+func TestBuildAndShutdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	logger, handle, err := Build(WithOutputFilePath(path), WithAddSource(false), WithSetDefault(false))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	logger.Info("test")
+
+	if err := handle.Flush(context.Background()); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"msg":"test"`) {
+		t.Errorf("wrong logs output data %q", data)
+	}
+
+	handles.mu.Lock()
+	_, registered := handles.set[handle]
+	handles.mu.Unlock()
+	if !registered {
+		t.Error("expected handle with opened file to be registered")
+	}
+
+	if err := Shutdown(context.Background()); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+
+	handles.mu.Lock()
+	_, registered = handles.set[handle]
+	handles.mu.Unlock()
+	if registered {
+		t.Error("expected handle to be unregistered after shutdown")
+	}
+
+	if err := logger.Handler().Handle(context.Background(), Record{}); err == nil {
+		t.Error("expected error on writing to closed file")
+	}
+
+	_, handle, err = Build(WithSetDefault(false))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	handles.mu.Lock()
+	_, registered = handles.set[handle]
+	handles.mu.Unlock()
+	if registered {
+		t.Error("expected stdout handle not to be registered")
+	}
+}
@@ -2,10 +2,15 @@ package glog
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"io"
 	"log"
-	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/lmittmann/tint"
@@ -25,7 +30,31 @@ const (
 	defaultLogFile      = ""
 )
 
+// NewLogger creates a new logger, it exits the program if the logger can't be created.
+// Use NewLoggerE to handle construction errors.
 func NewLogger(opts ...LoggerOption) *Logger {
+	logger, _, err := NewLoggerE(opts...)
+	if err != nil {
+		logFatalf("Error on creating logger: %s\n", err.Error())
+	}
+
+	return logger
+}
+
+// NewLoggerE creates a new logger and returns closer releasing its output resources, e.g. the log file.
+// It returns an error if options are not valid or the output can't be opened.
+func NewLoggerE(opts ...LoggerOption) (*Logger, io.Closer, error) {
+	logger, handle, err := Build(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return logger, handle, nil
+}
+
+// Build creates a new logger and returns handle to flush and close its outputs.
+// It returns an error if options are not valid or the output can't be opened.
+func Build(opts ...LoggerOption) (*Logger, *Handle, error) {
 	config := &LoggerOptions{
 		Level:         defaultLevel,
 		AddSource:     defaultAddSource,
@@ -39,67 +68,377 @@ func NewLogger(opts ...LoggerOption) *Logger {
 		opt(config)
 	}
 
-	var logger *Logger
+	if err := config.validate(); err != nil {
+		return nil, nil, err
+	}
 
-	if config.CustomHandler != nil {
-		logger = New(config.CustomHandler)
-	} else {
-		options := &HandlerOptions{
-			AddSource: config.AddSource,
-			Level:     config.Level,
+	levels := config.LevelController
+	if levels == nil {
+		level := config.Level
+		if len(config.Sinks) > 0 {
+			// sinks filter records by their own levels, the logger passes records of all of them
+			level = config.Sinks[0].Level
+			for _, sink := range config.Sinks[1:] {
+				level = min(level, sink.Level)
+			}
 		}
+		if anyLevel, ok := config.NameLevels[AnyName]; ok {
+			// the AnyName level is the level of the logger, Reset restores it
+			level = anyLevel
+		}
+		levels = NewLevelController(level)
+	}
 
-		var logStream io.Writer = os.Stdout
-		var isatty bool
+	handle := newHandle()
+	handle.levels = levels
 
-		switch config.LogFilePath {
-		case "", "stdout", "/dev/stdout":
-			logStream = os.Stdout
-			isatty = true
-		default:
-			f, err := os.OpenFile(config.LogFilePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	var handler Handler
+
+	if config.CustomHandler != nil {
+		handler = withDedup(withSampling(withAsync(config.CustomHandler, config, handle), config, handle), config, handle)
+	} else {
+		if len(config.Sinks) > 0 {
+			handlers := make([]Handler, 0, len(config.Sinks))
+			for _, sink := range config.Sinks {
+				h, err := newOutputHandler(sink.Format, sink.Destination, sink.Level, config, handle)
+				if err != nil {
+					handle.Close()
+					return nil, nil, err
+				}
+				handlers = append(handlers, h)
+			}
+			handler = NewMultiHandler(handlers...)
+		} else {
+			h, err := newOutputHandler(config.OutputFormat, config.LogFilePath, lowestLevel, config, handle)
 			if err != nil {
-				logFatalf("Error on opening logging file: %s\n", err.Error())
+				return nil, nil, err
 			}
-			logStream = f
+			handler = h
 		}
+		// sinks keep their own levels, the logger and per-name levels can only raise them
+		nameLevels := NewNameLevels(levels)
 
-		var handler slog.Handler
+		for name, level := range config.NameLevels {
+			nameLevels.Set(name, level)
+		}
+		handle.nameLevels = nameLevels
 
-		switch config.OutputFormat {
-		case OutputFormatJSON:
-			handler = NewJSONHandler(logStream, options)
-		case OutputFormatTEXT:
-			opts := &tint.Options{
-				Level:      options.Level,
-				TimeFormat: time.DateTime,
-				NoColor:    !isatty,
-				AddSource:  options.AddSource,
-			}
-			handler = tint.NewHandler(logStream, opts)
+		rateLimits := NewRateLimits()
+		for _, limit := range config.RateLimits {
+			rateLimits.Set(limit)
+		}
+		handle.rateLimits = rateLimits
+
+		handler = withDedup(withSampling(withAsync(handler, config, handle), config, handle), config, handle)
+		if len(config.RateLimits) > 0 {
+			// the numbers of suppressed records are logged by Close before the wrapped handlers are closed
+			handle.add(rateLimits)
 		}
+		handler = NewNameLevelHandler(NewRateLimitHandler(handler, rateLimits), nameLevels)
+	}
 
-		logger = New(handler)
+	if config.Redaction != nil {
+		// attributes added by the trace and context handlers are redacted too
+		redaction, err := NewRedactionHandler(handler, *config.Redaction)
+		if err != nil {
+			handle.Close()
+			return nil, nil, err
+		}
+		handler = redaction
+	}
+	if config.CustomHandler == nil || config.TraceAttrs {
+		// other custom handlers are used as given, see WithCustomHandler
+		handler = NewContextHandler(handler, ContextHandlerOptions{TraceAttrs: config.TraceAttrs})
 	}
 
+	logger := New(handler)
+
 	if config.SetDefault {
 		SetDefault(logger)
 	}
 
-	return logger
+	return logger, handle, nil
+}
+
+// withAsync wraps handler with AsyncHandler if the asynchronous output is enabled, it is added to handle
+// after the sinks, so queued records are handled before the sinks are closed
+func withAsync(handler Handler, config *LoggerOptions, handle *Handle) Handler {
+	if config.Async == nil {
+		return handler
+	}
+
+	async := NewAsyncHandler(handler, *config.Async)
+	handle.add(async)
+	return async
+}
+
+// withSampling wraps handler with SamplingHandler if sampling is enabled, the last summary is logged
+// when handle is closed
+func withSampling(handler Handler, config *LoggerOptions, handle *Handle) Handler {
+	if config.Sampling == nil {
+		return handler
+	}
+
+	sampling := NewSamplingHandler(handler, *config.Sampling)
+	handle.add(sampling)
+	return sampling
+}
+
+// withDedup wraps handler with DedupHandler if the dedup window is set, repeated records are logged
+// when handle is closed
+func withDedup(handler Handler, config *LoggerOptions, handle *Handle) Handler {
+	if config.DedupWindow <= 0 {
+		return handler
+	}
+
+	dedup := NewDedupHandler(handler, config.DedupWindow)
+	handle.add(dedup)
+	return dedup
+}
+
+// newOutputHandler creates handler writing records in format to destination and adds opened resources to handle
+func newOutputHandler(format OutputFormat, destination string, level Leveler, config *LoggerOptions, handle *Handle) (Handler, error) {
+	options := &HandlerOptions{
+		AddSource: config.AddSource,
+		Level:     level,
+	}
+
+	w, closer, isatty, err := openOutput(format, destination, config)
+	if err != nil {
+		return nil, err
+	}
+	handle.add(closer)
+
+	return newFormatHandler(format, w, isatty, options, config), nil
+}
+
+// openOutput opens destination of records in format, network destinations and local daemons
+// are supported by some formats only
+func openOutput(format OutputFormat, destination string, config *LoggerOptions) (io.Writer, io.Closer, bool, error) {
+	network, address, isNetwork := networkDestination(destination)
+
+	switch {
+	case format.isSyslog() && (isNetwork || destination == ""):
+		w, err := NewSyslogWriter(network, address, config.syslogOptions(format))
+		if err != nil {
+			return nil, nil, false, err
+		}
+		return w, w, false, nil
+	case format == OutputFormatJournal && (network == "unixgram" || destination == ""):
+		w, err := NewJournalWriter(address)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		return w, w, false, nil
+	case format == OutputFormatGELF && isNetwork:
+		w, err := NewGELFWriter(network, address)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		return w, w, false, nil
+	case format == OutputFormatOTLP && (network == "http" || network == "https"):
+		w, err := NewOTLPExporter(destination, config.OTLPExporter)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		return w, w, false, nil
+	case isNetwork:
+		return nil, nil, false, fmt.Errorf("network destination '%s' is not supported by %s output format", destination, format)
+	default:
+		return openLogStream(destination, config)
+	}
+}
+
+func openLogStream(path string, config *LoggerOptions) (io.Writer, io.Closer, bool, error) {
+	if w, ok := standardStream(path); ok {
+		return w, nil, true, nil
+	}
+
+	switch {
+	case config.ReopenOnSIGHUP:
+		f, err := NewReopenableFile(path)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("open log file: %w", err)
+		}
+		f.ReopenOnSignal(syscall.SIGHUP)
+		return f, f, false, nil
+	case config.Rotation.enabled():
+		w, err := NewRotatingWriter(path, config.Rotation)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("open log file: %w", err)
+		}
+		return w, w, false, nil
+	default:
+		f, err := openLogFile(path)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("open log file: %w", err)
+		}
+		return f, f, false, nil
+	}
+}
+
+func newFormatHandler(format OutputFormat, w io.Writer, isatty bool, options *HandlerOptions, config *LoggerOptions) Handler {
+	switch format {
+	case OutputFormatTEXT:
+		opts := &tint.Options{
+			Level:      options.Level,
+			TimeFormat: time.DateTime,
+			NoColor:    !isatty,
+			AddSource:  options.AddSource,
+		}
+		return tint.NewHandler(w, opts)
+	case OutputFormatLogfmt:
+		return NewLogfmtHandler(w, options)
+	case OutputFormatECS:
+		return NewECSHandler(w, options)
+	case OutputFormatGELF:
+		return NewGELFHandler(w, options)
+	case OutputFormatSyslog, OutputFormatSyslog3164:
+		return NewSyslogHandler(w, options, config.syslogOptions(format))
+	case OutputFormatJournal:
+		return NewJournalHandler(w, options)
+	case OutputFormatOTLP:
+		return NewOTLPHandler(w, options, config.Resource)
+	default:
+		return NewJSONHandler(w, options)
+	}
+}
+
+func standardStream(path string) (io.Writer, bool) {
+	switch path {
+	case "", "stdout", "/dev/stdout":
+		return os.Stdout, true
+	case "stderr", "/dev/stderr":
+		return os.Stderr, true
+	default:
+		return nil, false
+	}
+}
+
+// networkDestination splits destinations like "udp://host:port" into network and address
+func networkDestination(destination string) (network, address string, ok bool) {
+	network, address, ok = strings.Cut(destination, "://")
+	if !ok || network == "" {
+		return "", "", false
+	}
+	return network, address, true
+}
+
+func isFileDestination(destination string) bool {
+	if _, ok := standardStream(destination); ok {
+		return false
+	}
+	_, _, network := networkDestination(destination)
+	return !network
+}
+
+// SinkOptions describes an output of the logger added with WithSink
+type SinkOptions struct {
+	Format      OutputFormat
+	Destination string
+	Level       Level
 }
 
 type LoggerOptions struct {
-	Level         Level
-	AddSource     bool
-	OutputFormat  OutputFormat
-	SetDefault    bool
-	LogFilePath   string
-	CustomHandler Handler
+	Level           Level
+	AddSource       bool
+	OutputFormat    OutputFormat
+	SetDefault      bool
+	LogFilePath     string
+	Rotation        RotationOptions
+	ReopenOnSIGHUP  bool
+	Syslog          SyslogOptions
+	Resource        []Attr
+	OTLPExporter    OTLPExporterOptions
+	Async           *AsyncOptions
+	Sampling        *SamplingOptions
+	RateLimits      []RateLimit
+	DedupWindow     time.Duration
+	Redaction       *RedactionOptions
+	TraceAttrs      bool
+	Sinks           []SinkOptions
+	LevelController *LevelController
+	NameLevels      map[string]Level
+	CustomHandler   Handler
+
+	// errs collects errors of options which parse their values
+	errs []error
 }
 
 type LoggerOption func(*LoggerOptions)
 
+func (o *LoggerOptions) syslogOptions(format OutputFormat) SyslogOptions {
+	syslog := o.Syslog
+	syslog.RFC3164 = format == OutputFormatSyslog3164
+	return syslog
+}
+
+func (o *LoggerOptions) validate() error {
+	errs := append([]error(nil), o.errs...)
+
+	if !o.OutputFormat.valid() {
+		errs = append(errs, fmt.Errorf("unknown output format %d", o.OutputFormat))
+	}
+
+	fileOptions := o.Rotation.enabled() || o.ReopenOnSIGHUP
+	if o.CustomHandler != nil && (o.LogFilePath != "" || fileOptions || len(o.Sinks) > 0) {
+		errs = append(errs, errors.New("custom handler conflicts with log file and sink options"))
+	}
+	if o.CustomHandler != nil && len(o.NameLevels) > 0 {
+		errs = append(errs, errors.New("custom handler conflicts with per-name levels"))
+	}
+	if o.CustomHandler != nil && len(o.RateLimits) > 0 {
+		errs = append(errs, errors.New("custom handler conflicts with rate limits"))
+	}
+	for _, limit := range o.RateLimits {
+		if limit.Rate < 0 || limit.Burst < 0 {
+			errs = append(errs, fmt.Errorf("rate limit of '%s' must not be negative", limit.Name))
+		}
+	}
+	if len(o.Sinks) > 0 && o.LogFilePath != "" {
+		errs = append(errs, errors.New("sinks conflict with log file path, add the file as a sink"))
+	}
+
+	hasFile := isFileDestination(o.LogFilePath)
+	files := make(map[string]struct{}, len(o.Sinks))
+	for _, sink := range o.Sinks {
+		if !sink.Format.valid() {
+			errs = append(errs, fmt.Errorf("unknown sink output format %d", sink.Format))
+		}
+		if isFileDestination(sink.Destination) {
+			hasFile = true
+			// writers of the same file would interleave records and rotate the file of each other
+			path, err := filepath.Abs(sink.Destination)
+			if err != nil {
+				path = filepath.Clean(sink.Destination)
+			}
+			if _, ok := files[path]; ok {
+				errs = append(errs, fmt.Errorf("log file '%s' is used by several sinks", sink.Destination))
+			}
+			files[path] = struct{}{}
+		}
+	}
+	if fileOptions && !hasFile {
+		errs = append(errs, errors.New("log file rotation and reopening require log file path"))
+	}
+	if o.ReopenOnSIGHUP && o.Rotation.enabled() {
+		errs = append(errs, errors.New("reopening on SIGHUP conflicts with log file rotation"))
+	}
+	if o.Sampling != nil && (o.Sampling.First <= 0 || o.Sampling.Thereafter < 0 || o.Sampling.Interval < 0) {
+		errs = append(errs, errors.New("sampling first must be positive, thereafter and interval must not be negative"))
+	}
+	if o.Redaction != nil {
+		if err := o.Redaction.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if o.Rotation.MaxSize < 0 || o.Rotation.MaxAge < 0 || o.Rotation.MaxBackups < 0 {
+		errs = append(errs, errors.New("log file rotation options must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
 // WithLevel logger option sets the log level, if not set, the default level is Info
 func WithLevel(level string) LoggerOption {
 	return func(o *LoggerOptions) {
@@ -112,6 +451,48 @@ func WithLevel(level string) LoggerOption {
 	}
 }
 
+// WithLevelController logger option sets the level controller, use it to share the runtime
+// adjustable level between several loggers. It takes precedence over WithLevel.
+func WithLevelController(controller *LevelController) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.LevelController = controller
+	}
+}
+
+// WithNameLevel logger option sets the level for loggers with the name added by WithName or the NameKey
+// attribute, AnyName sets the level for loggers without an own level
+func WithNameLevel(name string, level Level) LoggerOption {
+	return func(o *LoggerOptions) {
+		if o.NameLevels == nil {
+			o.NameLevels = make(map[string]Level)
+		}
+		o.NameLevels[name] = level
+	}
+}
+
+// WithNameLevels logger option sets per-name levels written like "http-access=debug,*=info"
+func WithNameLevels(spec string) LoggerOption {
+	return func(o *LoggerOptions) {
+		levels, err := ParseNameLevels(spec)
+		if err != nil {
+			o.errs = append(o.errs, err)
+			return
+		}
+		for name, level := range levels {
+			WithNameLevel(name, level)(o)
+		}
+	}
+}
+
+// WithNameLevelsFromEnv logger option sets per-name levels from the GLOG_LEVELS environment variable
+func WithNameLevelsFromEnv() LoggerOption {
+	return func(o *LoggerOptions) {
+		if spec, ok := os.LookupEnv(NameLevelsEnv); ok {
+			WithNameLevels(spec)(o)
+		}
+	}
+}
+
 // WithAddSource logger option sets the add source option, which will add source file and line number to the log record
 func WithAddSource(addSource bool) LoggerOption {
 	return func(o *LoggerOptions) {
@@ -126,13 +507,163 @@ func WithOutputFormat(format OutputFormat) LoggerOption {
 	}
 }
 
-// WithOutputFilePath set output file path
+// WithOutputFilePath set output file path, "stdout", "stderr" or a network destination like "udp://host:port"
+// supported by the output format. Syslog formats write to the local syslog daemon
+// and the journal format writes to the systemd journal if the path is empty.
 func WithOutputFilePath(path string) LoggerOption {
 	return func(o *LoggerOptions) {
 		o.LogFilePath = path
 	}
 }
 
+// WithMaxSize logger option sets the maximum size in bytes of the log file before it gets rotated
+func WithMaxSize(size int64) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.Rotation.MaxSize = size
+	}
+}
+
+// WithMaxAge logger option sets the maximum time to retain rotated log files
+func WithMaxAge(age time.Duration) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.Rotation.MaxAge = age
+	}
+}
+
+// WithMaxBackups logger option sets the maximum number of rotated log files to retain
+func WithMaxBackups(count int) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.Rotation.MaxBackups = count
+	}
+}
+
+// WithCompress logger option enables gzip compression of rotated log files
+func WithCompress(compress bool) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.Rotation.Compress = compress
+	}
+}
+
+// WithLocalTime logger option sets local time instead of UTC for timestamps in rotated log file names
+func WithLocalTime(localTime bool) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.Rotation.LocalTime = localTime
+	}
+}
+
+// WithReopenOnSIGHUP logger option enables reopening of the log file when the process receives SIGHUP,
+// use it when the file is rotated by an external tool like logrotate
+func WithReopenOnSIGHUP(reopen bool) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.ReopenOnSIGHUP = reopen
+	}
+}
+
+// WithSyslogFacility logger option sets the facility of syslog messages
+func WithSyslogFacility(facility SyslogFacility) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.Syslog.Facility = facility
+	}
+}
+
+// WithSyslogAppName logger option sets the application name of syslog messages, the default is the program name
+func WithSyslogAppName(name string) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.Syslog.AppName = name
+	}
+}
+
+// WithSyslogTLSConfig logger option sets the TLS configuration of "tls://host:port" syslog destinations
+func WithSyslogTLSConfig(config *tls.Config) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.Syslog.TLSConfig = config
+	}
+}
+
+// WithResource logger option adds attributes of the resource producing records to OTLP output
+func WithResource(attrs ...Attr) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.Resource = append(o.Resource, attrs...)
+	}
+}
+
+// WithOTLPExporter logger option configures export of OTLP output to OTLP/HTTP endpoints, e.g. headers
+// for authentication and batching
+func WithOTLPExporter(opts OTLPExporterOptions) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.OTLPExporter = opts
+	}
+}
+
+// WithService logger option sets service.name and service.version resource attributes of OTLP output,
+// an empty version is omitted
+func WithService(name, version string) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.Resource = append(o.Resource, StringAttr(ServiceNameKey, name))
+		if version != "" {
+			o.Resource = append(o.Resource, StringAttr(ServiceVersionKey, version))
+		}
+	}
+}
+
+// WithSink logger option adds output writing records with the level and above in the format to
+// the destination, which is a file path, "stdout", "stderr" or a network destination supported by
+// the format. The option can be repeated, sinks replace the output set by WithOutputFormat and
+// WithOutputFilePath.
+func WithSink(format OutputFormat, destination string, level Level) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.Sinks = append(o.Sinks, SinkOptions{Format: format, Destination: destination, Level: level})
+	}
+}
+
+// WithAsync logger option passes records to the output in a background goroutine through a bounded queue,
+// flush or close the handle returned by Build to handle the queued records
+func WithAsync(opts AsyncOptions) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.Async = &opts
+	}
+}
+
+// WithSampling logger option caps the number of records with the same level and message per interval,
+// see SamplingHandler
+func WithSampling(opts SamplingOptions) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.Sampling = &opts
+	}
+}
+
+// WithRateLimit logger option limits the rate of records of the logger name at the level, records
+// exceeding the limit are dropped, see RateLimitHandler. The option can be repeated.
+func WithRateLimit(limit RateLimit) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.RateLimits = append(o.RateLimits, limit)
+	}
+}
+
+// WithDedup logger option suppresses records repeating a record with the same level, message and
+// attributes within the window, see DedupHandler
+func WithDedup(window time.Duration) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.DedupWindow = window
+	}
+}
+
+// WithRedaction logger option replaces secrets in records before they are written, see RedactionHandler.
+// It also applies to the custom handler.
+func WithRedaction(opts RedactionOptions) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.Redaction = &opts
+	}
+}
+
+// WithTraceAttrs logger option adds trace_id, span_id and trace_flags attributes of the trace context
+// of the context to records, see ContextHandler
+func WithTraceAttrs(enabled bool) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.TraceAttrs = enabled
+	}
+}
+
 // WithSetDefault logger option sets the set default option, which will set the created logger as default logger
 func WithSetDefault(setDefault bool) LoggerOption {
 	return func(o *LoggerOptions) {
@@ -140,14 +671,15 @@ func WithSetDefault(setDefault bool) LoggerOption {
 	}
 }
 
-// WithCustomHandler logger option sets the custom handler
+// WithCustomHandler logger option sets the custom handler. The handler isn't wrapped by ContextHandler unless
+// WithTraceAttrs is set, wrap it with NewContextHandler to log attributes of ContextWithAttrs.
 func WithCustomHandler(handler Handler) LoggerOption {
 	return func(o *LoggerOptions) {
 		o.CustomHandler = handler
 	}
 }
 
-// WithAttrs returns logger with attributes
+// WithAttrs returns logger with attributes, the logger isn't stored in ctx, see ContextWithAttrs
 func WithAttrs(ctx context.Context, attrs ...Attr) *Logger {
 	logger := L(ctx)
 	for _, attr := range attrs {
@@ -2,10 +2,14 @@ package glog
 
 import (
 	"context"
+	"log"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewLogger(t *testing.T) {
@@ -125,23 +129,68 @@ func TestNewLogger(t *testing.T) {
 		t.Errorf("wrong logs output data")
 	}
 
-	file1, err := os.CreateTemp("", "log")
-	if err != nil {
-		t.Errorf("on create tempfile, expected no error, got %s", err.Error())
-	}
-	defer os.Remove(file1.Name())
-
-	err = os.Chmod(file1.Name(), 0444)
-	if err != nil {
-		t.Errorf("cant change temp file permissions")
-	}
-
 	fatal := false
 	logFatalf = func(_ string, _ ...any) {
 		fatal = true
 	}
-	logger = NewLogger(WithOutputFilePath(file1.Name()), WithAddSource(false))
+	defer func() {
+		logFatalf = func(format string, v ...any) {
+			log.Fatalf(format, v...)
+		}
+	}()
+	// permissions are not checked for root, so the file is placed into a missing directory
+	logger = NewLogger(WithOutputFilePath(filepath.Join(t.TempDir(), "missing", "log")), WithAddSource(false))
 	if fatal == false {
 		t.Errorf("expected fatal exit")
 	}
 }
+
+func TestNewLoggerE(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, closer, err := NewLoggerE(WithOutputFilePath(path), WithAddSource(false), WithSetDefault(false))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	logger.Info("test")
+	if err := closer.Close(); err != nil {
+		t.Errorf("expected no error on close, got %s", err.Error())
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"msg":"test"`) {
+		t.Errorf("wrong logs output data %q", data)
+	}
+
+	_, closer, err = NewLoggerE(WithSetDefault(false))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if err := closer.Close(); err != nil {
+		t.Errorf("expected no error on close, got %s", err.Error())
+	}
+
+	testCases := []struct {
+		name string
+		opts []LoggerOption
+	}{
+		{"unknown output format", []LoggerOption{WithOutputFormat(OutputFormat(100))}},
+		{"unwritable path", []LoggerOption{WithOutputFilePath(filepath.Join(t.TempDir(), "missing", "log"))}},
+		{"custom handler with file", []LoggerOption{WithCustomHandler(NewDiscardHandler()), WithOutputFilePath(path)}},
+		{"custom handler with rotation", []LoggerOption{WithCustomHandler(NewDiscardHandler()), WithMaxSize(10)}},
+		{"rotation without file", []LoggerOption{WithMaxBackups(1)}},
+		{"reopen without file", []LoggerOption{WithReopenOnSIGHUP(true)}},
+		{"reopen with rotation", []LoggerOption{WithOutputFilePath(path), WithReopenOnSIGHUP(true), WithMaxSize(10)}},
+		{"negative rotation", []LoggerOption{WithOutputFilePath(path), WithMaxAge(-time.Second)}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			logger, closer, err := NewLoggerE(append(testCase.opts, WithSetDefault(false))...)
+			if err == nil {
+				t.Error("expected error, got no error")
+			}
+			if logger != nil || closer != nil {
+				t.Error("expected nil logger and closer")
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package glog
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	logfmtTimeFormat   = "2006-01-02T15:04:05.000Z07:00"
+	logfmtMaxBufferCap = 64 << 10
+)
+
+var logfmtBufPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 1024)
+		return &b
+	},
+}
+
+// LogfmtHandler writes records as logfmt lines: key=value pairs separated by spaces, values with
+// spaces, quotes, equal signs or control characters are quoted. Attributes of groups are flattened
+// with dots, e.g. "request.method=GET".
+type LogfmtHandler struct {
+	opts         HandlerOptions
+	preformatted []byte
+	groups       []string
+	prefix       string
+
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func NewLogfmtHandler(w io.Writer, opts *HandlerOptions) *LogfmtHandler {
+	h := &LogfmtHandler{w: w, mu: &sync.Mutex{}}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+func (h *LogfmtHandler) Enabled(_ context.Context, level Level) bool {
+	minLevel := LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+func (h *LogfmtHandler) Handle(_ context.Context, r Record) error {
+	bufp := logfmtBufPool.Get().(*[]byte)
+	buf := (*bufp)[:0]
+	defer func() {
+		if cap(buf) <= logfmtMaxBufferCap {
+			*bufp = buf[:0]
+			logfmtBufPool.Put(bufp)
+		}
+	}()
+
+	if h.opts.ReplaceAttr == nil {
+		// fast path without boxing of builtin values
+		if !r.Time.IsZero() {
+			buf = appendLogfmtKey(buf, "", TimeKey)
+			buf = r.Time.AppendFormat(buf, logfmtTimeFormat)
+		}
+		buf = appendLogfmtKey(buf, "", LevelKey)
+		buf = appendLogfmtString(buf, r.Level.String())
+		if h.opts.AddSource && r.PC != 0 {
+			buf = h.appendBuiltin(buf, slog.Any(SourceKey, recordSource(r)))
+		}
+		buf = appendLogfmtKey(buf, "", MessageKey)
+		buf = appendLogfmtString(buf, r.Message)
+	} else {
+		if !r.Time.IsZero() {
+			buf = h.appendBuiltin(buf, slog.Time(TimeKey, r.Time))
+		}
+		buf = h.appendBuiltin(buf, slog.Any(LevelKey, r.Level))
+		if h.opts.AddSource && r.PC != 0 {
+			buf = h.appendBuiltin(buf, slog.Any(SourceKey, recordSource(r)))
+		}
+		buf = h.appendBuiltin(buf, slog.String(MessageKey, r.Message))
+	}
+
+	buf = append(buf, h.preformatted...)
+	r.Attrs(func(attr Attr) bool {
+		buf = h.appendAttr(buf, h.prefix, h.groups, attr)
+		return true
+	})
+
+	buf = append(buf, '\n')
+	line := buf
+	if line[0] == ' ' {
+		line = line[1:]
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(line)
+
+	return err
+}
+
+func (h *LogfmtHandler) WithAttrs(attrs []Attr) Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.preformatted = append([]byte(nil), h.preformatted...)
+	for _, attr := range attrs {
+		h2.preformatted = h.appendAttr(h2.preformatted, h.prefix, h.groups, attr)
+	}
+	return &h2
+}
+
+func (h *LogfmtHandler) WithGroup(name string) Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(append([]string(nil), h.groups...), name)
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// appendBuiltin appends time, level, source or message attribute applying ReplaceAttr
+func (h *LogfmtHandler) appendBuiltin(buf []byte, attr Attr) []byte {
+	if rep := h.opts.ReplaceAttr; rep != nil {
+		attr = rep(nil, attr)
+		attr.Value = attr.Value.Resolve()
+		if attr.Equal(Attr{}) {
+			return buf
+		}
+	}
+
+	switch v := attr.Value.Any().(type) {
+	case *Source:
+		buf = appendLogfmtKey(buf, "", attr.Key)
+		return appendLogfmtString(buf, v.File+":"+strconv.Itoa(v.Line))
+	case time.Time:
+		buf = appendLogfmtKey(buf, "", attr.Key)
+		return v.AppendFormat(buf, logfmtTimeFormat)
+	}
+
+	return h.appendResolved(buf, "", nil, attr)
+}
+
+func (h *LogfmtHandler) appendAttr(buf []byte, prefix string, groups []string, attr Attr) []byte {
+	attr.Value = attr.Value.Resolve()
+	if rep := h.opts.ReplaceAttr; rep != nil && attr.Value.Kind() != KindGroup {
+		attr = rep(groups, attr)
+		attr.Value = attr.Value.Resolve()
+	}
+	return h.appendResolved(buf, prefix, groups, attr)
+}
+
+// appendResolved appends attribute with resolved value, members of groups are flattened
+func (h *LogfmtHandler) appendResolved(buf []byte, prefix string, groups []string, attr Attr) []byte {
+	if attr.Equal(Attr{}) {
+		return buf
+	}
+
+	if attr.Value.Kind() == KindGroup {
+		members := attr.Value.Group()
+		if len(members) == 0 {
+			return buf
+		}
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+			groups = append(groups[:len(groups):len(groups)], attr.Key)
+		}
+		for _, member := range members {
+			buf = h.appendAttr(buf, prefix, groups, member)
+		}
+		return buf
+	}
+
+	buf = appendLogfmtKey(buf, prefix, attr.Key)
+	return appendLogfmtValue(buf, attr.Value)
+}
+
+func recordSource(r Record) *Source {
+	frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+	return &Source{Function: frame.Function, File: frame.File, Line: frame.Line}
+}
+
+func appendLogfmtKey(buf []byte, prefix, key string) []byte {
+	buf = append(buf, ' ')
+	buf = appendLogfmtKeyPart(buf, prefix)
+	buf = appendLogfmtKeyPart(buf, key)
+	return append(buf, '=')
+}
+
+// appendLogfmtKeyPart appends key replacing characters which are not allowed in unquoted keys with '_'
+func appendLogfmtKeyPart(buf []byte, key string) []byte {
+	for _, r := range key {
+		if r == '=' || r == '"' || r == utf8.RuneError || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			r = '_'
+		}
+		buf = utf8.AppendRune(buf, r)
+	}
+	return buf
+}
+
+func appendLogfmtValue(buf []byte, v Value) []byte {
+	switch v.Kind() {
+	case KindString:
+		return appendLogfmtString(buf, v.String())
+	case KindInt64:
+		return strconv.AppendInt(buf, v.Int64(), 10)
+	case KindUint64:
+		return strconv.AppendUint(buf, v.Uint64(), 10)
+	case KindFloat64:
+		return strconv.AppendFloat(buf, v.Float64(), 'g', -1, 64)
+	case KindBool:
+		return strconv.AppendBool(buf, v.Bool())
+	case KindDuration:
+		return appendLogfmtString(buf, v.Duration().String())
+	case KindTime:
+		return v.Time().AppendFormat(buf, time.RFC3339Nano)
+	}
+
+	switch x := v.Any().(type) {
+	case Level:
+		return appendLogfmtString(buf, x.String())
+	case error:
+		return appendLogfmtString(buf, x.Error())
+	case encoding.TextMarshaler:
+		data, err := x.MarshalText()
+		if err != nil {
+			return appendLogfmtString(buf, "!ERROR:"+err.Error())
+		}
+		return appendLogfmtString(buf, string(data))
+	case []byte:
+		return appendLogfmtString(buf, string(x))
+	default:
+		return appendLogfmtString(buf, fmt.Sprint(x))
+	}
+}
+
+func appendLogfmtString(buf []byte, s string) []byte {
+	if needsLogfmtQuoting(s) {
+		return strconv.AppendQuote(buf, s)
+	}
+	return append(buf, s...)
+}
+
+func needsLogfmtQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			if b <= ' ' || b == '=' || b == '"' || b == '\\' || b == 0x7f {
+				return true
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
@@ -0,0 +1,223 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parseLogfmt parses a logfmt line into key-value pairs keeping their order
+func parseLogfmt(line string) ([][2]string, error) {
+	var pairs [][2]string
+	line = strings.TrimSuffix(line, "\n")
+	for len(line) > 0 {
+		line = strings.TrimLeft(line, " ")
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("missing key in %q", line)
+		}
+		key := line[:eq]
+		if strings.ContainsAny(key, " \"") {
+			return nil, fmt.Errorf("invalid key %q", key)
+		}
+		line = line[eq+1:]
+
+		var value string
+		if strings.HasPrefix(line, `"`) {
+			quoted, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted value in %q: %w", line, err)
+			}
+			value, _ = strconv.Unquote(quoted)
+			line = line[len(quoted):]
+		} else {
+			end := strings.IndexByte(line, ' ')
+			if end < 0 {
+				end = len(line)
+			}
+			value = line[:end]
+			line = line[end:]
+		}
+		if len(line) > 0 && line[0] != ' ' {
+			return nil, fmt.Errorf("missing separator in %q", line)
+		}
+		pairs = append(pairs, [2]string{key, value})
+	}
+	return pairs, nil
+}
+
+func logfmtPairs(t *testing.T, buf *bytes.Buffer) [][2]string {
+	t.Helper()
+	pairs, err := parseLogfmt(buf.String())
+	if err != nil {
+		t.Fatalf("parse %q error: %s", buf.String(), err.Error())
+	}
+	buf.Reset()
+	return pairs
+}
+
+func removeTime(groups []string, a Attr) Attr {
+	if a.Key == TimeKey && len(groups) == 0 {
+		return Attr{}
+	}
+	return a
+}
+
+func TestLogfmtHandlerRoundTrip(t *testing.T) {
+	strs := []string{
+		"plain",
+		"",
+		"with space",
+		`with "quotes"`,
+		"key=value",
+		"new\nline",
+		"tab\there",
+		`back\slash`,
+		"unicode ✓ ok",
+		"юникод",
+		"nul\x00byte",
+		"invalid \xff utf8",
+	}
+
+	var buf bytes.Buffer
+	logger := New(NewLogfmtHandler(&buf, &HandlerOptions{Level: LevelDebug, ReplaceAttr: removeTime}))
+
+	for _, s := range strs {
+		logger.Info(s, StringAttr("value", s))
+		pairs := logfmtPairs(t, &buf)
+		if len(pairs) != 3 {
+			t.Fatalf("expected 3 pairs, got %v", pairs)
+		}
+		expected := strings.ToValidUTF8(s, "�")
+		if pairs[1] != [2]string{"msg", expected} && pairs[1] != [2]string{"msg", s} {
+			t.Errorf("expected msg %q, got %q", s, pairs[1][1])
+		}
+		if pairs[2][1] != s && pairs[2][1] != expected {
+			t.Errorf("expected value %q, got %q", s, pairs[2][1])
+		}
+	}
+}
+
+type logfmtTestValuer struct{}
+
+func (logfmtTestValuer) LogValue() Value {
+	return GroupValue(StringAttr("user", "admin"), IntAttr("id", 7))
+}
+
+func TestLogfmtHandlerValues(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(NewLogfmtHandler(&buf, &HandlerOptions{ReplaceAttr: removeTime})).
+		With(StringAttr("service", "api")).
+		WithGroup("request").
+		With(StringAttr("method", "GET"))
+
+	logger.Info(
+		"done",
+		IntAttr("status", 200),
+		Uint64Attr("size", 12),
+		Float64Attr("ratio", 0.25),
+		BoolAttr("cached", true),
+		DurationAttr("elapsed", 1500*time.Millisecond),
+		Time("at", time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)),
+		Any("err", errors.New("broken pipe")),
+		Any("level", LevelWarn),
+		Any("bytes", []byte("raw data")),
+		Any("auth", logfmtTestValuer{}),
+		Group("headers", StringAttr("agent", "curl"), Group("empty")),
+		StringAttr("bad key=\"x\"", "v"),
+	)
+
+	expected := [][2]string{
+		{"level", "INFO"},
+		{"msg", "done"},
+		{"service", "api"},
+		{"request.method", "GET"},
+		{"request.status", "200"},
+		{"request.size", "12"},
+		{"request.ratio", "0.25"},
+		{"request.cached", "true"},
+		{"request.elapsed", "1.5s"},
+		{"request.at", "2024-05-01T10:00:00Z"},
+		{"request.err", "broken pipe"},
+		{"request.level", "WARN"},
+		{"request.bytes", "raw data"},
+		{"request.auth.user", "admin"},
+		{"request.auth.id", "7"},
+		{"request.headers.agent", "curl"},
+		{"request.bad_key__x_", "v"},
+	}
+	pairs := logfmtPairs(t, &buf)
+	if fmt.Sprint(pairs) != fmt.Sprint(expected) {
+		t.Errorf("expected\n%v\ngot\n%v", expected, pairs)
+	}
+}
+
+func TestLogfmtHandlerBuiltins(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewLogfmtHandler(&buf, &HandlerOptions{AddSource: true})
+	logger := New(h)
+
+	if h.Enabled(context.Background(), LevelDebug) {
+		t.Error("expected handler to be disabled for debug level by default")
+	}
+	if h.WithGroup("") != h || h.WithAttrs(nil) != h {
+		t.Error("expected the same handler for empty group and attributes")
+	}
+
+	logger.Warn("source")
+	pairs := logfmtPairs(t, &buf)
+	if len(pairs) != 4 || pairs[0][0] != "time" || pairs[1] != [2]string{"level", "WARN"} || pairs[2][0] != "source" {
+		t.Fatalf("unexpected pairs %v", pairs)
+	}
+	if _, err := time.Parse(logfmtTimeFormat, pairs[0][1]); err != nil {
+		t.Errorf("unexpected time format %q", pairs[0][1])
+	}
+	if !strings.Contains(pairs[2][1], "logfmt_test.go:") {
+		t.Errorf("unexpected source %q", pairs[2][1])
+	}
+
+	// builtins can be renamed and removed
+	h = NewLogfmtHandler(&buf, &HandlerOptions{ReplaceAttr: func(groups []string, a Attr) Attr {
+		switch a.Key {
+		case TimeKey, LevelKey:
+			return Attr{}
+		case MessageKey:
+			return StringAttr("message", a.Value.String())
+		}
+		return a
+	}})
+	h.Handle(context.Background(), slog.NewRecord(time.Now(), LevelInfo, "renamed", 0))
+	if line := buf.String(); line != "message=renamed\n" {
+		t.Errorf("unexpected line %q", line)
+	}
+}
+
+func TestLogfmtHandlerAllocs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(NewLogfmtHandler(&buf, nil)).With(StringAttr("service", "api"))
+	ctx := context.Background()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		buf.Reset()
+		logger.LogAttrs(ctx, LevelInfo, "request", StringAttr("method", "GET"), IntAttr("status", 200))
+	})
+	if allocs > 0 {
+		t.Errorf("expected no allocations per record, got %.1f", allocs)
+	}
+}
+
+func TestLogfmtOutputFormat(t *testing.T) {
+	f, err := ParseOutputFormat("logfmt")
+	if err != nil || f != OutputFormatLogfmt || f.String() != "logfmt" {
+		t.Errorf("expected logfmt output format, got %s, err %v", f, err)
+	}
+	if _, ok := newFormatHandler(OutputFormatLogfmt, &bytes.Buffer{}, false, &HandlerOptions{}, &LoggerOptions{}).(*LogfmtHandler); !ok {
+		t.Error("expected LogfmtHandler")
+	}
+}
@@ -18,6 +18,19 @@ const (
 
 type loggedHttpAuthInfoContextKey struct{}
 
+// accessLogContextKey marks contexts of access log records with the fields of their attribute keys, e.g. for
+// output formats mapping the fields
+type accessLogContextKey struct{}
+
+// accessLogFieldsFromContext returns the fields of attribute keys of the access log record logged with ctx
+func accessLogFieldsFromContext(ctx context.Context) (map[string]AccessLogField, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	fields, ok := ctx.Value(accessLogContextKey{}).(map[string]AccessLogField)
+	return fields, ok
+}
+
 func ContextWithLoggedHttpAuthInfo(ctx context.Context, authInfo LogValuer) context.Context {
 	return context.WithValue(ctx, loggedHttpAuthInfoContextKey{}, authInfo)
 }
@@ -118,47 +131,257 @@ func getUserIP(r *http.Request) net.IP {
 	}
 }
 
-func NewHttpAccessLogMiddleware(name string) func(next http.Handler) http.Handler {
+// AccessLogField is a field of access log records, its value is the default attribute key
+type AccessLogField string
+
+const (
+	AccessLogFieldName      AccessLogField = AccessLogNameKey
+	AccessLogFieldMethod    AccessLogField = "method"
+	AccessLogFieldIP        AccessLogField = "ip"
+	AccessLogFieldStatus    AccessLogField = "status"
+	AccessLogFieldQuery     AccessLogField = "query"
+	AccessLogFieldSize      AccessLogField = "size"
+	AccessLogFieldLength    AccessLogField = "length"
+	AccessLogFieldDuration  AccessLogField = "duration"
+	AccessLogFieldAgent     AccessLogField = "agent"
+	AccessLogFieldReferer   AccessLogField = "referer"
+	AccessLogFieldAuth      AccessLogField = "auth"
+	AccessLogFieldRequestID AccessLogField = RequestIDKey
+)
+
+// defaultAccessLogFields are the fields logged by default in the order of attributes
+var defaultAccessLogFields = []AccessLogField{
+	AccessLogFieldName, AccessLogFieldMethod, AccessLogFieldIP, AccessLogFieldStatus, AccessLogFieldQuery,
+	AccessLogFieldSize, AccessLogFieldLength, AccessLogFieldDuration, AccessLogFieldAgent, AccessLogFieldReferer,
+	AccessLogFieldAuth, AccessLogFieldRequestID,
+}
+
+const defaultAccessLogMessage = "Request"
+
+// AccessLogOptions configures the access log middleware
+type AccessLogOptions struct {
+	// Name is the value of the name attribute, empty omits it
+	Name string
+	// Fields are the logged fields, nil logs all fields
+	Fields []AccessLogField
+	// Keys renames attributes of the fields, the name field is not renamed, per-name levels and rate limits
+	// look for the NameKey attribute
+	Keys map[AccessLogField]string
+	// Message of records, the default is "Request"
+	Message string
+	// Level returns the level of the record, by default (or if nil) server errors are logged at the error
+	// level, client errors at the warn level and other requests at the info level
+	Level func(r *http.Request, status int) Level
+	// Logger logs records, the default is the logger of the request context
+	Logger *Logger
+	// Attrs returns custom attributes of the request and the response appended to records
+	Attrs func(r *http.Request, w ResponseWriter) []Attr
+	// RequestIDHeader is the header with the request ID, the default is X-Request-ID, empty disables request IDs
+	RequestIDHeader string
+	// GenerateRequestID returns IDs of requests without a valid ID, the default is NewRequestID
+	GenerateRequestID func() string
+}
+
+type AccessLogOption func(*AccessLogOptions)
+
+// WithAccessLogName access log option sets the value of the name attribute
+func WithAccessLogName(name string) AccessLogOption {
+	return func(o *AccessLogOptions) {
+		o.Name = name
+	}
+}
+
+// WithAccessLogFields access log option sets the logged fields
+func WithAccessLogFields(fields ...AccessLogField) AccessLogOption {
+	return func(o *AccessLogOptions) {
+		o.Fields = fields
+	}
+}
+
+// WithAccessLogKey access log option renames the attribute of the field, except the name field
+func WithAccessLogKey(field AccessLogField, key string) AccessLogOption {
+	return func(o *AccessLogOptions) {
+		if o.Keys == nil {
+			o.Keys = make(map[AccessLogField]string)
+		}
+		o.Keys[field] = key
+	}
+}
+
+// WithAccessLogMessage access log option sets the message of records
+func WithAccessLogMessage(message string) AccessLogOption {
+	return func(o *AccessLogOptions) {
+		o.Message = message
+	}
+}
+
+// WithAccessLogLevel access log option sets the function returning the level of records
+func WithAccessLogLevel(level func(r *http.Request, status int) Level) AccessLogOption {
+	return func(o *AccessLogOptions) {
+		o.Level = level
+	}
+}
+
+// WithAccessLogLogger access log option sets the logger used instead of the logger of the request context,
+// the logger with the request ID is put into the request context
+func WithAccessLogLogger(logger *Logger) AccessLogOption {
+	return func(o *AccessLogOptions) {
+		o.Logger = logger
+	}
+}
+
+// WithAccessLogAttrs access log option sets the function returning custom attributes of records
+func WithAccessLogAttrs(attrs func(r *http.Request, w ResponseWriter) []Attr) AccessLogOption {
+	return func(o *AccessLogOptions) {
+		o.Attrs = attrs
+	}
+}
+
+// WithRequestIDHeader access log option sets the header the request ID is read from and echoed in,
+// empty header disables request IDs
+func WithRequestIDHeader(header string) AccessLogOption {
+	return func(o *AccessLogOptions) {
+		o.RequestIDHeader = header
+	}
+}
+
+// WithRequestIDGenerator access log option sets the generator of request IDs
+func WithRequestIDGenerator(generate func() string) AccessLogOption {
+	return func(o *AccessLogOptions) {
+		o.GenerateRequestID = generate
+	}
+}
+
+// defaultAccessLogLevel logs server errors at the error level and client errors at the warn level
+func defaultAccessLogLevel(_ *http.Request, status int) Level {
+	if status >= http.StatusInternalServerError {
+		return LevelError
+	} else if status >= http.StatusBadRequest {
+		return LevelWarn
+	}
+	return LevelInfo
+}
+
+// NewHttpAccessLogMiddleware creates middleware logging requests with the name, see NewHttpAccessLogMiddlewareWith
+func NewHttpAccessLogMiddleware(name string, opts ...AccessLogOption) func(next http.Handler) http.Handler {
+	return NewHttpAccessLogMiddlewareWith(append([]AccessLogOption{WithAccessLogName(name)}, opts...)...)
+}
+
+// NewHttpAccessLogMiddlewareWith creates middleware logging requests. The request ID is read from the request
+// header or generated, echoed in the response header and added to the logger in the request context,
+// so records logged with L(r.Context()) carry it in the request_id attribute. The trace context of the
+// traceparent and tracestate headers is put into the request context, see WithTraceAttrs.
+func NewHttpAccessLogMiddlewareWith(opts ...AccessLogOption) func(next http.Handler) http.Handler {
+	options := AccessLogOptions{
+		Fields:            defaultAccessLogFields,
+		Message:           defaultAccessLogMessage,
+		Level:             defaultAccessLogLevel,
+		RequestIDHeader:   DefaultRequestIDHeader,
+		GenerateRequestID: NewRequestID,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.Level == nil {
+		options.Level = defaultAccessLogLevel
+	}
+
+	fields := make(map[AccessLogField]string, len(options.Fields))
+	for _, field := range options.Fields {
+		key := string(field)
+		if k, ok := options.Keys[field]; ok && k != "" && field != AccessLogFieldName {
+			key = k
+		}
+		fields[field] = key
+	}
+	if options.Name == "" {
+		delete(fields, AccessLogFieldName)
+	}
+	keyFields := make(map[string]AccessLogField, len(fields))
+	for field, key := range fields {
+		keyFields[key] = field
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
 			rw := newResponseWriter(w)
-			next.ServeHTTP(rw, r)
 
-			status := rw.Status()
+			if tc, ok := TraceContextFromRequest(r); ok {
+				r = r.WithContext(ContextWithTraceContext(r.Context(), tc))
+			}
 
-			level := LevelInfo
-			if status >= http.StatusInternalServerError {
-				level = LevelError
-			} else if status >= http.StatusBadRequest {
-				level = LevelWarn
+			logger := options.Logger
+			if logger == nil {
+				logger = L(r.Context())
 			}
 
-			attrs := make([]Attr, 0, 11)
-			attrs = append(
-				attrs,
-				StringAttr(AccessLogNameKey, name),
-				StringAttr("method", r.Method),
-				StringAttr("ip", getUserIP(r).String()),
-				IntAttr("status", status),
-				StringAttr("query", r.URL.RequestURI()),
-				StringAttr("size", byteCountIEC(rw.Size())),
-				IntAttr("length", rw.Size()),
-				Float64Attr("duration", time.Since(start).Seconds()),
-			)
-
-			if ua := r.UserAgent(); ua != "" {
-				attrs = append(attrs, StringAttr("agent", ua))
+			requestID := ""
+			if options.RequestIDHeader != "" {
+				requestID = r.Header.Get(options.RequestIDHeader)
+				if !validRequestID(requestID) {
+					requestID = options.GenerateRequestID()
+				}
+				w.Header().Set(options.RequestIDHeader, requestID)
+
+				// the fields only select attributes of the access log record
+				ctx := ContextWithRequestID(r.Context(), requestID)
+				r = r.WithContext(ContextWithLogger(ctx, logger.With(StringAttr(RequestIDKey, requestID))))
 			}
-			if ref := r.Referer(); ref != "" {
-				attrs = append(attrs, StringAttr("referer", ref))
+
+			next.ServeHTTP(rw, r)
+
+			status := rw.Status()
+
+			attrs := make([]Attr, 0, len(fields))
+			for _, field := range options.Fields {
+				key, ok := fields[field]
+				if !ok {
+					continue
+				}
+				switch field {
+				case AccessLogFieldName:
+					attrs = append(attrs, StringAttr(key, options.Name))
+				case AccessLogFieldMethod:
+					attrs = append(attrs, StringAttr(key, r.Method))
+				case AccessLogFieldIP:
+					attrs = append(attrs, StringAttr(key, getUserIP(r).String()))
+				case AccessLogFieldStatus:
+					attrs = append(attrs, IntAttr(key, status))
+				case AccessLogFieldQuery:
+					attrs = append(attrs, StringAttr(key, r.URL.RequestURI()))
+				case AccessLogFieldSize:
+					attrs = append(attrs, StringAttr(key, byteCountIEC(rw.Size())))
+				case AccessLogFieldLength:
+					attrs = append(attrs, IntAttr(key, rw.Size()))
+				case AccessLogFieldDuration:
+					attrs = append(attrs, Float64Attr(key, time.Since(start).Seconds()))
+				case AccessLogFieldAgent:
+					if ua := r.UserAgent(); ua != "" {
+						attrs = append(attrs, StringAttr(key, ua))
+					}
+				case AccessLogFieldReferer:
+					if ref := r.Referer(); ref != "" {
+						attrs = append(attrs, StringAttr(key, ref))
+					}
+				case AccessLogFieldAuth:
+					if authInfo, ok := loggedAuthInfoFromContext(r.Context()); ok {
+						attrs = append(attrs, Any(key, authInfo))
+					}
+				case AccessLogFieldRequestID:
+					if requestID != "" {
+						attrs = append(attrs, StringAttr(key, requestID))
+					}
+				}
 			}
 
-			if authInfo, ok := loggedAuthInfoFromContext(r.Context()); ok {
-				attrs = append(attrs, Any("auth", authInfo))
+			if options.Attrs != nil {
+				attrs = append(attrs, options.Attrs(r, rw)...)
 			}
 
-			L(r.Context()).LogAttrs(r.Context(), level, "Request", attrs...)
+			ctx := context.WithValue(r.Context(), accessLogContextKey{}, keyFields)
+			logger.LogAttrs(ctx, options.Level(r, status), options.Message, attrs...)
 		})
 	}
 }
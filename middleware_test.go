@@ -2,13 +2,16 @@ package glog
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 type AuthInfo struct {
@@ -335,3 +338,153 @@ func TestResponseWriterHijack(t *testing.T) {
 		}
 	})
 }
+
+func TestHttpAccessLogMiddlewareRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(NewJSONHandler(&buf, nil))
+	ctx := ContextWithLogger(context.Background(), logger)
+
+	var handlerID string
+	httpHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerID, _ = RequestIDFromContext(r.Context())
+		L(r.Context()).Info("Handled")
+	})
+
+	// incoming ID is propagated
+	req := httptest.NewRequest("GET", "http://testing", nil).WithContext(ctx)
+	req.Header.Set("X-Request-ID", "abc-123")
+	w := httptest.NewRecorder()
+	NewHttpAccessLogMiddleware("access")(httpHandler).ServeHTTP(w, req)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || handlerID != "abc-123" || w.Header().Get("X-Request-ID") != "abc-123" {
+		t.Fatalf("unexpected request ID %q, header %q, lines %q", handlerID, w.Header().Get("X-Request-ID"), lines)
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, `"request_id":"abc-123"`) {
+			t.Errorf("expected request ID in %s", line)
+		}
+	}
+
+	// invalid ID is replaced with a generated one in the custom header
+	buf.Reset()
+	req = httptest.NewRequest("GET", "http://testing", nil).WithContext(ctx)
+	req.Header.Set("X-Correlation-ID", "bad id\n")
+	w = httptest.NewRecorder()
+	NewHttpAccessLogMiddleware("access", WithRequestIDHeader("X-Correlation-ID"))(httpHandler).ServeHTTP(w, req)
+
+	if id := w.Header().Get("X-Correlation-ID"); id != handlerID || len(id) != 36 || id[14] != '7' {
+		t.Errorf("expected generated UUIDv7, got %q, handler got %q", id, handlerID)
+	}
+	if strings.Count(buf.String(), `"request_id":"`+handlerID+`"`) != 2 {
+		t.Errorf("expected generated request ID in records %s", buf.String())
+	}
+
+	// custom generator and disabled request IDs
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "http://testing", nil).WithContext(ctx)
+	NewHttpAccessLogMiddleware("access", WithRequestIDGenerator(func() string { return "generated" }))(httpHandler).ServeHTTP(w, req)
+	if handlerID != "generated" {
+		t.Errorf("expected generated request ID, got %q", handlerID)
+	}
+
+	buf.Reset()
+	handlerID = ""
+	w = httptest.NewRecorder()
+	NewHttpAccessLogMiddleware("access", WithRequestIDHeader(""))(httpHandler).ServeHTTP(w, req)
+	if handlerID != "" || len(w.Header()) != 0 || strings.Contains(buf.String(), "request_id") {
+		t.Errorf("expected no request ID, got %q %v %s", handlerID, w.Header(), buf.String())
+	}
+}
+
+func TestNewRequestID(t *testing.T) {
+	id := NewRequestID()
+	if len(id) != 36 || id[8] != '-' || id[13] != '-' || id[14] != '7' || !strings.ContainsAny(id[19:20], "89ab") {
+		t.Errorf("unexpected UUIDv7 %q", id)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	if next := NewRequestID(); next <= id {
+		t.Errorf("expected %q to sort after %q", next, id)
+	}
+}
+
+func TestHttpAccessLogMiddlewareWith(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(NewJSONHandler(&buf, nil))
+	var contextLogger *Logger
+
+	middleware := NewHttpAccessLogMiddlewareWith(
+		WithAccessLogFields(AccessLogFieldMethod, AccessLogFieldStatus, AccessLogFieldQuery, AccessLogFieldRequestID),
+		WithAccessLogKey(AccessLogFieldQuery, "url"),
+		WithAccessLogKey(AccessLogFieldRequestID, "rid"),
+		WithAccessLogMessage("HTTP"),
+		WithAccessLogLevel(func(r *http.Request, status int) Level {
+			if r.URL.Path == "/health" {
+				return LevelDebug
+			}
+			return LevelInfo
+		}),
+		WithAccessLogLogger(logger),
+		WithAccessLogAttrs(func(r *http.Request, w ResponseWriter) []Attr {
+			return []Attr{StringAttr("route", r.URL.Path), StringAttr("type", w.Header().Get("Content-Type"))}
+		}),
+		WithRequestIDGenerator(func() string { return "42" }),
+	)
+	httpHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contextLogger = L(r.Context())
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	// the supplied logger is used instead of the logger of the request context
+	req := httptest.NewRequest("POST", "http://testing/tea?cups=2", nil).WithContext(ContextWithLogger(context.Background(), NewDiscardLogger()))
+	middleware(httpHandler).ServeHTTP(httptest.NewRecorder(), req)
+
+	m := decodeJSONLine(t, &buf)
+	expected := map[string]any{
+		"level": "INFO", "msg": "HTTP", "method": "POST", "status": float64(418), "url": "/tea?cups=2",
+		"rid": "42", "route": "/tea", "type": "text/plain",
+	}
+	if len(m) != len(expected)+1 {
+		t.Errorf("unexpected attributes %v", m)
+	}
+	for key, value := range expected {
+		if m[key] != value {
+			t.Errorf("expected %s %v, got %v", key, value, m[key])
+		}
+	}
+	contextLogger.Info("Handled")
+	if m := decodeJSONLine(t, &buf); m[RequestIDKey] != "42" {
+		t.Errorf("expected context logger with request ID, got %v", m)
+	}
+
+	req = httptest.NewRequest("GET", "http://testing/health", nil)
+	middleware(httpHandler).ServeHTTP(httptest.NewRecorder(), req)
+	if buf.Len() != 0 {
+		t.Errorf("expected health check at debug level to be dropped, got %s", buf.String())
+	}
+
+	// the name is omitted if not set
+	NewHttpAccessLogMiddlewareWith(WithAccessLogLogger(logger))(httpHandler).ServeHTTP(httptest.NewRecorder(), req)
+	if m := decodeJSONLine(t, &buf); m[AccessLogNameKey] != nil || m["msg"] != "Request" || m["level"] != "WARN" || m[RequestIDKey] == nil {
+		t.Errorf("unexpected default record %v", m)
+	}
+
+	// the fields don't affect the context logger, the name is not renamed and nil level is the default
+	NewHttpAccessLogMiddlewareWith(
+		WithAccessLogLogger(logger),
+		WithAccessLogName("access"),
+		WithAccessLogFields(AccessLogFieldName, AccessLogFieldStatus),
+		WithAccessLogKey(AccessLogFieldName, "service"),
+		WithAccessLogLevel(nil),
+	)(httpHandler).ServeHTTP(httptest.NewRecorder(), req)
+	m = decodeJSONLine(t, &buf)
+	if m[AccessLogNameKey] != "access" || m["service"] != nil || m[RequestIDKey] != nil || m["level"] != "WARN" {
+		t.Errorf("unexpected record %v", m)
+	}
+	contextLogger.Info("Handled")
+	if m := decodeJSONLine(t, &buf); m[RequestIDKey] == nil {
+		t.Errorf("expected context logger with request ID, got %v", m)
+	}
+}
@@ -0,0 +1,55 @@
+package glog
+
+import (
+	"context"
+	"errors"
+)
+
+// MultiHandler dispatches every record to several handlers, an error of one handler
+// doesn't prevent delivery to the others
+type MultiHandler struct {
+	handlers []Handler
+}
+
+func NewMultiHandler(handlers ...Handler) *MultiHandler {
+	return &MultiHandler{handlers: handlers}
+}
+
+// Enabled reports whether any of handlers is enabled for the level
+func (h *MultiHandler) Enabled(ctx context.Context, level Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *MultiHandler) Handle(ctx context.Context, r Record) error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if !handler.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *MultiHandler) WithAttrs(attrs []Attr) Handler {
+	handlers := make([]Handler, 0, len(h.handlers))
+	for _, handler := range h.handlers {
+		handlers = append(handlers, handler.WithAttrs(attrs))
+	}
+	return NewMultiHandler(handlers...)
+}
+
+func (h *MultiHandler) WithGroup(name string) Handler {
+	handlers := make([]Handler, 0, len(h.handlers))
+	for _, handler := range h.handlers {
+		handlers = append(handlers, handler.WithGroup(name))
+	}
+	return NewMultiHandler(handlers...)
+}
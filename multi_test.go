@@ -0,0 +1,143 @@
+package glog
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type levelRecordsHandler struct {
+	*RecordsHandler
+	level Level
+	err   error
+}
+
+func (h *levelRecordsHandler) Enabled(_ context.Context, level Level) bool { return level >= h.level }
+
+func (h *levelRecordsHandler) Handle(ctx context.Context, r Record) error {
+	if h.err != nil {
+		return h.err
+	}
+	return h.RecordsHandler.Handle(ctx, r)
+}
+
+func (h *levelRecordsHandler) WithAttrs(attrs []Attr) Handler {
+	return &levelRecordsHandler{RecordsHandler: h.RecordsHandler.WithAttrs(attrs).(*RecordsHandler), level: h.level, err: h.err}
+}
+
+func TestMultiHandler(t *testing.T) {
+	ctx := context.Background()
+	var debugRecords, warnRecords []Record
+	sinkErr := errors.New("sink error")
+
+	broken := &levelRecordsHandler{RecordsHandler: NewRecordsHandler(&[]Record{}), level: LevelDebug, err: sinkErr}
+	debug := &levelRecordsHandler{RecordsHandler: NewRecordsHandler(&debugRecords), level: LevelDebug}
+	warn := &levelRecordsHandler{RecordsHandler: NewRecordsHandler(&warnRecords), level: LevelWarn}
+
+	handler := NewMultiHandler(broken, debug, warn)
+	if !handler.Enabled(ctx, LevelDebug) {
+		t.Error("expected handler to be enabled for debug level")
+	}
+	if NewMultiHandler(warn).Enabled(ctx, LevelInfo) {
+		t.Error("expected handler to be disabled for info level")
+	}
+	if NewMultiHandler().Enabled(ctx, LevelError) {
+		t.Error("expected handler without sinks to be disabled")
+	}
+
+	logger := New(handler).With(StringAttr("service", "test"))
+	logger.Debug("debug message")
+	logger.Warn("warn message")
+
+	if err := handler.Handle(ctx, Record{Level: LevelInfo}); !errors.Is(err, sinkErr) {
+		t.Errorf("expected sink error, got %v", err)
+	}
+
+	if count := len(debugRecords); count != 3 {
+		t.Fatalf("expected 3 debug sink records, got %d", count)
+	}
+	if count := len(warnRecords); count != 1 {
+		t.Fatalf("expected 1 warn sink record, got %d", count)
+	}
+	if err := checkLogRecord(warnRecords[0], LevelWarn, "warn message", []Attr{StringAttr("service", "test")}); err != nil {
+		t.Errorf("check log record error: %s", err.Error())
+	}
+	if _, ok := handler.WithGroup("group").(*MultiHandler); !ok {
+		t.Error("expected WithGroup to return MultiHandler")
+	}
+}
+
+func TestWithSink(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "app.json")
+	textPath := filepath.Join(dir, "app.log")
+
+	logger, handle, err := Build(
+		WithSink(OutputFormatJSON, jsonPath, LevelDebug),
+		WithSink(OutputFormatTEXT, textPath, LevelInfo),
+		WithAddSource(false),
+		WithSetDefault(false),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	logger.Debug("debug message")
+	logger.Info("info message")
+	handle.Close()
+
+	data, _ := os.ReadFile(jsonPath)
+	if !strings.Contains(string(data), `"msg":"debug message"`) || !strings.Contains(string(data), `"msg":"info message"`) {
+		t.Errorf("wrong json sink output data %q", data)
+	}
+	data, _ = os.ReadFile(textPath)
+	if strings.Contains(string(data), "debug message") || !strings.Contains(string(data), "INF info message") {
+		t.Errorf("wrong text sink output data %q", data)
+	}
+
+	// the level controller and per-name levels raise levels of all sinks
+	jsonPath = filepath.Join(dir, "levels.json")
+	logger, handle, err = Build(
+		WithSink(OutputFormatJSON, jsonPath, LevelDebug),
+		WithNameLevel("worker", LevelWarn),
+		WithAddSource(false),
+		WithSetDefault(false),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	logger.Debug("debug passed")
+	WithName(logger, "worker").Info("worker dropped")
+	handle.LevelController().SetLevel(LevelError)
+	logger.Warn("warn dropped")
+	handle.NameLevels().Set("worker", LevelDebug)
+	WithName(logger, "worker").Debug("worker passed")
+	handle.Close()
+
+	data, _ = os.ReadFile(jsonPath)
+	if !strings.Contains(string(data), "debug passed") || !strings.Contains(string(data), "worker passed") ||
+		strings.Contains(string(data), "dropped") {
+		t.Errorf("wrong sink output data with levels %q", data)
+	}
+
+	testCases := []struct {
+		name string
+		opts []LoggerOption
+	}{
+		{"unknown sink format", []LoggerOption{WithSink(OutputFormat(100), "stdout", LevelInfo)}},
+		{"sink with log file path", []LoggerOption{WithSink(OutputFormatJSON, "stdout", LevelInfo), WithOutputFilePath(jsonPath)}},
+		{"sink with custom handler", []LoggerOption{WithSink(OutputFormatJSON, "stdout", LevelInfo), WithCustomHandler(NewDiscardHandler())}},
+		{"rotation without file sink", []LoggerOption{WithSink(OutputFormatJSON, "stderr", LevelInfo), WithMaxSize(10)}},
+		{"duplicate sink file", []LoggerOption{WithSink(OutputFormatJSON, jsonPath, LevelInfo), WithSink(OutputFormatTEXT, filepath.Join(dir, ".", filepath.Base(jsonPath)), LevelDebug)}},
+		{"unwritable sink", []LoggerOption{WithSink(OutputFormatJSON, filepath.Join(dir, "missing", "log"), LevelInfo)}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, _, err := Build(append(testCase.opts, WithSetDefault(false))...); err == nil {
+				t.Error("expected error, got no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package glog
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
+
+const (
+	// NameLevelsEnv is the environment variable with per-name levels like "http-access=debug,*=info"
+	NameLevelsEnv = "GLOG_LEVELS"
+	// AnyName sets the level of loggers without an own level in per-name levels
+	AnyName = "*"
+
+	lowestLevel = Level(math.MinInt)
+)
+
+// ParseNameLevels parses per-name levels written like "http-access=debug,memory_stat=debug,*=warn"
+func ParseNameLevels(spec string) (map[string]Level, error) {
+	levels := make(map[string]Level)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid name level '%s', expected name=level", item)
+		}
+		var level Level
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+			return nil, fmt.Errorf("invalid level for name '%s': %w", name, err)
+		}
+		levels[name] = level
+	}
+	return levels, nil
+}
+
+// levelSetter is a fallback leveler which takes AnyName levels, like LevelController
+type levelSetter interface {
+	Leveler
+	SetLevel(level Level)
+	Reset()
+}
+
+// NameLevels holds level thresholds per logger name, the name is taken from the NameKey attribute.
+// Loggers without an own threshold use the AnyName one or the fallback leveler. It is safe for
+// concurrent use and can be changed at runtime.
+type NameLevels struct {
+	fallback Leveler
+
+	mu       sync.RWMutex
+	levels   map[string]Level
+	anyLevel *Level
+}
+
+// NewNameLevels creates per-name levels with the fallback leveler used for names without an own level
+func NewNameLevels(fallback Leveler) *NameLevels {
+	return &NameLevels{fallback: fallback, levels: make(map[string]Level)}
+}
+
+// Set sets the level for the name, AnyName sets the level for loggers without an own level.
+// If the fallback is a LevelController, AnyName sets its level.
+func (nl *NameLevels) Set(name string, level Level) {
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+
+	if name != AnyName {
+		nl.levels[name] = level
+	} else if setter, ok := nl.fallback.(levelSetter); ok {
+		setter.SetLevel(level)
+	} else {
+		nl.anyLevel = &level
+	}
+}
+
+// Delete removes the level of the name, if the fallback is a LevelController, AnyName resets its level
+func (nl *NameLevels) Delete(name string) {
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+
+	if name != AnyName {
+		delete(nl.levels, name)
+	} else if setter, ok := nl.fallback.(levelSetter); ok {
+		setter.Reset()
+	} else {
+		nl.anyLevel = nil
+	}
+}
+
+// Parse sets levels written like "http-access=debug,*=info", see ParseNameLevels
+func (nl *NameLevels) Parse(spec string) error {
+	levels, err := ParseNameLevels(spec)
+	if err != nil {
+		return err
+	}
+	for name, level := range levels {
+		nl.Set(name, level)
+	}
+	return nil
+}
+
+// Level returns the level threshold for the name
+func (nl *NameLevels) Level(name string) Level {
+	nl.mu.RLock()
+	defer nl.mu.RUnlock()
+
+	if level, ok := nl.levels[name]; ok {
+		return level
+	}
+	return nl.defaultLevel()
+}
+
+// Levels returns a copy of per-name levels including the AnyName one
+func (nl *NameLevels) Levels() map[string]Level {
+	nl.mu.RLock()
+	defer nl.mu.RUnlock()
+
+	levels := make(map[string]Level, len(nl.levels)+1)
+	for name, level := range nl.levels {
+		levels[name] = level
+	}
+	if nl.anyLevel != nil {
+		levels[AnyName] = *nl.anyLevel
+	}
+	return levels
+}
+
+func (nl *NameLevels) defaultLevel() Level {
+	if nl.anyLevel != nil {
+		return *nl.anyLevel
+	}
+	if nl.fallback == nil {
+		return lowestLevel
+	}
+	return nl.fallback.Level()
+}
+
+// anyNameLevel returns the threshold of loggers without an own level
+func (nl *NameLevels) anyNameLevel() Level {
+	nl.mu.RLock()
+	defer nl.mu.RUnlock()
+
+	return nl.defaultLevel()
+}
+
+// lowestNameLevel returns the lowest threshold of all names, names passed with records of unnamed
+// loggers can have any of them
+func (nl *NameLevels) lowestNameLevel() Level {
+	nl.mu.RLock()
+	defer nl.mu.RUnlock()
+
+	lowest := nl.defaultLevel()
+	for _, level := range nl.levels {
+		lowest = min(lowest, level)
+	}
+	return lowest
+}
+
+// NameLevelHandler drops records below the level threshold of the logger name, names are taken
+// from the NameKey attribute added with WithName, Logger.With or passed with the record. Loggers
+// without a name are enabled at the lowest threshold, records are dropped by the threshold of the
+// name passed with them.
+type NameLevelHandler struct {
+	next    Handler
+	levels  *NameLevels
+	name    string
+	named   bool
+	grouped bool
+}
+
+func NewNameLevelHandler(next Handler, levels *NameLevels) *NameLevelHandler {
+	return &NameLevelHandler{next: next, levels: levels}
+}
+
+func (h *NameLevelHandler) Enabled(ctx context.Context, level Level) bool {
+	var threshold Level
+	switch {
+	case h.named:
+		threshold = h.levels.Level(h.name)
+	case h.grouped:
+		// names passed with records are inside the group
+		threshold = h.levels.anyNameLevel()
+	default:
+		threshold = h.levels.lowestNameLevel()
+	}
+	return level >= threshold && h.next.Enabled(ctx, level)
+}
+
+func (h *NameLevelHandler) Handle(ctx context.Context, r Record) error {
+	name := h.name
+	if !h.grouped {
+		if n, ok := nameFromRecord(r); ok {
+			name = n
+		}
+	}
+	if r.Level < h.levels.Level(name) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *NameLevelHandler) WithAttrs(attrs []Attr) Handler {
+	h2 := *h
+	if !h.grouped {
+		if name, ok := nameFromAttrs(attrs); ok {
+			h2.name, h2.named = name, true
+		}
+	}
+	h2.next = h.next.WithAttrs(attrs)
+	return &h2
+}
+
+func (h *NameLevelHandler) WithGroup(name string) Handler {
+	h2 := *h
+	if name != "" {
+		h2.grouped = true
+	}
+	h2.next = h.next.WithGroup(name)
+	return &h2
+}
+
+func nameFromAttrs(attrs []Attr) (string, bool) {
+	name, found := "", false
+	for _, attr := range attrs {
+		if attr.Key == NameKey {
+			name, found = attr.Value.Resolve().String(), true
+		}
+	}
+	return name, found
+}
+
+func nameFromRecord(r Record) (string, bool) {
+	name, found := "", false
+	r.Attrs(func(attr Attr) bool {
+		if attr.Key == NameKey {
+			name, found = attr.Value.Resolve().String(), true
+		}
+		return true
+	})
+	return name, found
+}
@@ -0,0 +1,156 @@
+package glog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseNameLevels(t *testing.T) {
+	levels, err := ParseNameLevels(" http-access=debug, memory_stat=DEBUG+2,*=warn,")
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	expected := map[string]Level{"http-access": LevelDebug, "memory_stat": LevelDebug + 2, AnyName: LevelWarn}
+	if len(levels) != len(expected) {
+		t.Errorf("expected %v, got %v", expected, levels)
+	}
+	for name, level := range expected {
+		if levels[name] != level {
+			t.Errorf("expected %s level for %s, got %s", level, name, levels[name])
+		}
+	}
+
+	for _, spec := range []string{"http-access", "=debug", "http-access=verbose"} {
+		if _, err := ParseNameLevels(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestNameLevelHandler(t *testing.T) {
+	ctx := context.Background()
+	var logRecords []Record
+
+	fallback := NewLevelController(LevelInfo)
+	levels := NewNameLevels(fallback)
+	if err := levels.Parse("http-access=debug,noisy=error"); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	logger := New(NewNameLevelHandler(NewRecordsHandler(&logRecords), levels))
+
+	if !logger.Enabled(ctx, LevelDebug) {
+		t.Error("expected unnamed logger to be enabled for debug level of names passed with records")
+	}
+	if logger.WithGroup("g").Enabled(ctx, LevelDebug) {
+		t.Error("expected grouped unnamed logger to be disabled for debug level")
+	}
+	if !WithName(logger, "http-access").Enabled(ctx, LevelDebug) {
+		t.Error("expected access logger to be enabled for debug level")
+	}
+
+	noisy := WithName(logger, "noisy")
+	if noisy.Enabled(ctx, LevelWarn) {
+		t.Error("expected noisy logger to be disabled for warn level")
+	}
+	noisy.Warn("dropped")
+	noisy.Error("passed noisy")
+
+	logger.Debug("dropped")
+	logger.Debug("passed by record name", StringAttr(NameKey, "http-access"))
+	WithName(logger, "http-access").Debug("passed access")
+	logger.Info("passed unnamed")
+	logger.Warn("dropped by record name", StringAttr(NameKey, "noisy"))
+
+	// names inside groups are not logger names
+	logger.WithGroup("g").With(StringAttr(NameKey, "http-access")).Debug("dropped")
+	WithName(logger, "http-access").WithGroup("g").Debug("passed grouped access")
+
+	fallback.SetLevel(LevelError)
+	logger.Warn("dropped")
+	// AnyName sets the level of the fallback controller
+	levels.Set(AnyName, LevelWarn)
+	logger.Warn("passed any name")
+	if fallback.Level() != LevelWarn {
+		t.Errorf("expected fallback level to be set, got %s", fallback.Level())
+	}
+	levels.Delete(AnyName)
+	levels.Delete("noisy")
+	noisy.Debug("dropped")
+	noisy.Info("passed noisy after delete")
+
+	var messages []string
+	for _, r := range logRecords {
+		messages = append(messages, r.Message)
+	}
+	expected := "passed noisy,passed by record name,passed access,passed unnamed,passed grouped access,passed any name,passed noisy after delete"
+	if got := strings.Join(messages, ","); got != expected {
+		t.Errorf("expected records %q, got %q", expected, got)
+	}
+
+	if got := levels.Levels(); len(got) != 1 || got["http-access"] != LevelDebug {
+		t.Errorf("unexpected levels %v", got)
+	}
+}
+
+func TestBuildNameLevels(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv(NameLevelsEnv, "memory_stat=debug,*=warn")
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, handle, err := Build(
+		WithOutputFilePath(path),
+		WithNameLevelsFromEnv(),
+		WithNameLevel("http-access", LevelDebug),
+		WithAddSource(false),
+		WithSetDefault(false),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	defer handle.Close()
+
+	MemoryStatisticLogging(WithName(logger, "memory_stat"), LevelDebug)
+	WithName(logger, "http-access").Debug("Request")
+	logger.Info("dropped info")
+	handle.NameLevels().Set("worker", LevelInfo)
+	WithName(logger, "worker").Info("worker info")
+
+	if WithName(logger, "other").Enabled(ctx, LevelInfo) {
+		t.Error("expected other logger to be disabled for info level")
+	}
+
+	data, _ := os.ReadFile(path)
+	output := string(data)
+	for _, msg := range []string{"runtime MemStats", "Request", "worker info"} {
+		if !strings.Contains(output, `"msg":"`+msg+`"`) {
+			t.Errorf("expected record %q in output %q", msg, output)
+		}
+	}
+	if strings.Contains(output, "dropped info") {
+		t.Errorf("unexpected record in output %q", output)
+	}
+
+	// AnyName sets the level of the logger, it can be changed at runtime
+	anyLogger, handle, err := Build(WithNameLevel(AnyName, LevelInfo), WithLevel("error"), WithSetDefault(false))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	defer handle.Close()
+	if handle.LevelController().Level() != LevelInfo {
+		t.Errorf("expected info level, got %s", handle.LevelController().Level())
+	}
+	handle.LevelController().SetLevel(LevelDebug)
+	if !anyLogger.Enabled(ctx, LevelDebug) {
+		t.Error("expected logger to follow the level controller")
+	}
+
+	if _, _, err := Build(WithNameLevels("http-access=verbose"), WithSetDefault(false)); err == nil {
+		t.Error("expected error for invalid name levels")
+	}
+	if _, _, err := Build(WithNameLevel("a", LevelDebug), WithCustomHandler(NewDiscardHandler()), WithSetDefault(false)); err == nil {
+		t.Error("expected error for custom handler with name levels")
+	}
+}
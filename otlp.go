@@ -0,0 +1,573 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	otlpScopeName     = "github.com/kda47/glog"
+	otlpLogsPath      = "/v1/logs"
+	ServiceNameKey    = "service.name"
+	ServiceVersionKey = "service.version"
+
+	defaultOTLPBatchSize    = 512
+	defaultOTLPInterval     = time.Second
+	defaultOTLPMaxQueueSize = 2048
+	defaultOTLPTimeout      = 10 * time.Second
+)
+
+var errOTLPQueueFull = errors.New("OTLP queue is full, log record dropped")
+
+// otlpSeverityNumber maps level to OpenTelemetry severity number: debug is 5, info is 9, warn is 13
+// and error is 17, levels between them map to the numbers between
+func otlpSeverityNumber(level Level) int {
+	return max(1, min(24, int(level)+9))
+}
+
+// OTLPHandler writes records as OpenTelemetry logs in the OTLP/JSON encoding, one ExportLogsServiceRequest
+// per Write call ended by a newline. Attributes of groups are written as nested key-value lists, the trace
+// and span identifiers are taken from the trace context of the context, see ContextWithTraceContext.
+type OTLPHandler struct {
+	opts     HandlerOptions
+	resource []byte
+	attrs    []Attr
+	groups   []string
+
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+// NewOTLPHandler creates OTLP handler, resource attributes describe the service, e.g. service.name
+func NewOTLPHandler(w io.Writer, opts *HandlerOptions, resource []Attr) *OTLPHandler {
+	h := &OTLPHandler{w: w, mu: &sync.Mutex{}}
+	if opts != nil {
+		h.opts = *opts
+	}
+
+	var buf bytes.Buffer
+	appendOTLPKeyValues(&buf, resource)
+	h.resource = buf.Bytes()
+
+	return h
+}
+
+func (h *OTLPHandler) Enabled(_ context.Context, level Level) bool {
+	minLevel := LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+func (h *OTLPHandler) Handle(ctx context.Context, r Record) error {
+	now := time.Now()
+	t := r.Time
+	if t.IsZero() {
+		t = now
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"resourceLogs":[{"resource":{"attributes":`)
+	buf.Write(h.resource)
+	buf.WriteString(`},"scopeLogs":[{"scope":{"name":"` + otlpScopeName + `"},"logRecords":[{`)
+
+	buf.WriteString(`"timeUnixNano":"` + strconv.FormatInt(t.UnixNano(), 10) + `"`)
+	buf.WriteString(`,"observedTimeUnixNano":"` + strconv.FormatInt(now.UnixNano(), 10) + `"`)
+	buf.WriteString(`,"severityNumber":` + strconv.Itoa(otlpSeverityNumber(r.Level)))
+	buf.WriteString(`,"severityText":`)
+	appendJSONString(&buf, r.Level.String())
+	buf.WriteString(`,"body":{"stringValue":`)
+	appendJSONString(&buf, r.Message)
+	buf.WriteString(`}`)
+
+	attrs := make([]Attr, 0, r.NumAttrs())
+	r.Attrs(func(attr Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	attrs = insertGroupAttrs(h.attrs, h.groups, attrs)
+	if h.opts.AddSource && r.PC != 0 {
+		src := recordSource(r)
+		attrs = append(
+			attrs,
+			StringAttr("code.file.path", src.File),
+			IntAttr("code.line.number", src.Line),
+			StringAttr("code.function.name", src.Function),
+		)
+	}
+	buf.WriteString(`,"attributes":`)
+	appendOTLPKeyValues(&buf, attrs)
+
+	if tc, ok := TraceContextFromContext(ctx); ok {
+		buf.WriteString(`,"traceId":"` + tc.TraceID.String() + `","spanId":"` + tc.SpanID.String() + `"`)
+		buf.WriteString(`,"flags":` + strconv.Itoa(int(tc.Flags)))
+	}
+	buf.WriteString("}]}]}]}\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf.Bytes())
+
+	return err
+}
+
+func (h *OTLPHandler) WithAttrs(attrs []Attr) Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.attrs = insertGroupAttrs(h.attrs, h.groups, attrs)
+	return &h2
+}
+
+func (h *OTLPHandler) WithGroup(name string) Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &h2
+}
+
+// insertGroupAttrs returns copy of attrs with added attributes in the nested groups, the last attribute is
+// extended if it is the group, so attributes added after WithGroup share one group
+func insertGroupAttrs(attrs []Attr, groups []string, added []Attr) []Attr {
+	attrs = attrs[:len(attrs):len(attrs)]
+	if len(groups) == 0 {
+		return append(attrs, added...)
+	}
+	if len(added) == 0 {
+		return attrs
+	}
+
+	if n := len(attrs); n > 0 && attrs[n-1].Key == groups[0] && attrs[n-1].Value.Kind() == KindGroup {
+		members := insertGroupAttrs(attrs[n-1].Value.Group(), groups[1:], added)
+		return append(attrs[:n-1:n-1], Attr{Key: groups[0], Value: GroupValue(members...)})
+	}
+	members := insertGroupAttrs(nil, groups[1:], added)
+	return append(attrs, Attr{Key: groups[0], Value: GroupValue(members...)})
+}
+
+// appendOTLPKeyValues appends JSON array of key-value pairs, members of groups without a key are inlined
+func appendOTLPKeyValues(buf *bytes.Buffer, attrs []Attr) {
+	buf.WriteByte('[')
+	first := true
+	var appendAttrs func(attrs []Attr)
+	appendAttrs = func(attrs []Attr) {
+		for _, attr := range attrs {
+			attr.Value = attr.Value.Resolve()
+			if attr.Equal(Attr{}) {
+				continue
+			}
+			if attr.Value.Kind() == KindGroup && attr.Key == "" {
+				appendAttrs(attr.Value.Group())
+				continue
+			}
+			if attr.Value.Kind() == KindGroup && len(attr.Value.Group()) == 0 {
+				continue
+			}
+
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			buf.WriteString(`{"key":`)
+			appendJSONString(buf, attr.Key)
+			buf.WriteString(`,"value":`)
+			appendOTLPValue(buf, attr.Value)
+			buf.WriteByte('}')
+		}
+	}
+	appendAttrs(attrs)
+	buf.WriteByte(']')
+}
+
+// appendOTLPValue appends AnyValue, durations are written in nanoseconds
+func appendOTLPValue(buf *bytes.Buffer, v Value) {
+	switch v.Kind() {
+	case KindString:
+		buf.WriteString(`{"stringValue":`)
+		appendJSONString(buf, v.String())
+	case KindInt64:
+		buf.WriteString(`{"intValue":"` + strconv.FormatInt(v.Int64(), 10) + `"`)
+	case KindUint64:
+		if v.Uint64() > math.MaxInt64 {
+			buf.WriteString(`{"stringValue":"` + strconv.FormatUint(v.Uint64(), 10) + `"`)
+			break
+		}
+		buf.WriteString(`{"intValue":"` + strconv.FormatUint(v.Uint64(), 10) + `"`)
+	case KindFloat64:
+		buf.WriteString(`{"doubleValue":`)
+		if f := v.Float64(); math.IsNaN(f) || math.IsInf(f, 0) {
+			appendJSONString(buf, strconv.FormatFloat(f, 'g', -1, 64))
+		} else {
+			buf.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+		}
+	case KindBool:
+		buf.WriteString(`{"boolValue":` + strconv.FormatBool(v.Bool()))
+	case KindDuration:
+		buf.WriteString(`{"intValue":"` + strconv.FormatInt(v.Duration().Nanoseconds(), 10) + `"`)
+	case KindTime:
+		buf.WriteString(`{"stringValue":`)
+		appendJSONString(buf, v.Time().Format(time.RFC3339Nano))
+	case KindGroup:
+		buf.WriteString(`{"kvlistValue":{"values":`)
+		appendOTLPKeyValues(buf, v.Group())
+		buf.WriteByte('}')
+	default:
+		switch x := v.Any().(type) {
+		case []byte:
+			buf.WriteString(`{"bytesValue":"` + base64.StdEncoding.EncodeToString(x) + `"`)
+		case error:
+			buf.WriteString(`{"stringValue":`)
+			appendJSONString(buf, x.Error())
+		case encoding.TextMarshaler:
+			buf.WriteString(`{"stringValue":`)
+			data, err := x.MarshalText()
+			if err != nil {
+				appendJSONString(buf, "!ERROR:"+err.Error())
+			} else {
+				appendJSONString(buf, string(data))
+			}
+		default:
+			buf.WriteString(`{"stringValue":`)
+			appendJSONString(buf, v.String())
+		}
+	}
+	buf.WriteByte('}')
+}
+
+// OTLPExporterOptions configures OTLPExporter, zero values are replaced by defaults
+type OTLPExporterOptions struct {
+	// Headers are added to export requests, e.g. for authentication
+	Headers map[string]string
+	// BatchSize is the maximum number of log records in one request, the default is 512
+	BatchSize int
+	// Interval is the maximum time records wait for export, the default is one second
+	Interval time.Duration
+	// MaxQueueSize is the maximum number of queued records, new records are dropped when the queue
+	// is full, the default is 2048
+	MaxQueueSize int
+	// Timeout is the timeout of export requests, the default is 10 seconds
+	Timeout time.Duration
+	// Client sends requests, the default is a client without a timeout of its own
+	Client *http.Client
+}
+
+// OTLPExporter exports OTLP/JSON logs written by OTLPHandler to an OTLP/HTTP endpoint in batches.
+// Write doesn't block, records are queued and exported in the background. Records of a batch with
+// the same resource and scope are exported in one resourceLogs and scopeLogs entry.
+type OTLPExporter struct {
+	endpoint string
+	opts     OTLPExporterOptions
+
+	mu      sync.Mutex
+	pending []otlpQueuedRecord
+	closed  bool
+	dropped atomic.Uint64
+
+	// exportMu serializes exports of the background goroutine and Flush
+	exportMu sync.Mutex
+
+	// ctx of background exports is canceled when Close stops waiting for them
+	ctx    context.Context
+	cancel context.CancelFunc
+	ready  chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewOTLPExporter creates exporter to the OTLP/HTTP endpoint, "/v1/logs" is added to endpoints without a path
+func NewOTLPExporter(endpoint string, opts OTLPExporterOptions) (*OTLPExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint '%s'", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = otlpLogsPath
+	}
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultOTLPBatchSize
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultOTLPInterval
+	}
+	if opts.MaxQueueSize <= 0 {
+		opts.MaxQueueSize = defaultOTLPMaxQueueSize
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultOTLPTimeout
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &OTLPExporter{
+		endpoint: u.String(),
+		opts:     opts,
+		ctx:      ctx,
+		cancel:   cancel,
+		ready:    make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go e.run()
+
+	return e, nil
+}
+
+// otlpQueuedRecord is a queued log record with the resource and scope it was written with
+type otlpQueuedRecord struct {
+	resource json.RawMessage
+	scope    json.RawMessage
+	record   json.RawMessage
+}
+
+// Write queues log records of one OTLP/JSON ExportLogsServiceRequest
+func (e *OTLPExporter) Write(p []byte) (int, error) {
+	var req struct {
+		ResourceLogs []struct {
+			Resource  json.RawMessage `json:"resource"`
+			ScopeLogs []struct {
+				Scope      json.RawMessage   `json:"scope"`
+				LogRecords []json.RawMessage `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+	if err := json.Unmarshal(p, &req); err != nil {
+		return 0, fmt.Errorf("invalid OTLP/JSON logs: %w", err)
+	}
+	var records []otlpQueuedRecord
+	for _, rl := range req.ResourceLogs {
+		for _, sl := range rl.ScopeLogs {
+			for _, record := range sl.LogRecords {
+				records = append(records, otlpQueuedRecord{resource: rl.Resource, scope: sl.Scope, record: record})
+			}
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return 0, os.ErrClosed
+	}
+	if len(e.pending)+len(records) > e.opts.MaxQueueSize {
+		e.dropped.Add(uint64(len(records)))
+		return 0, errOTLPQueueFull
+	}
+	e.pending = append(e.pending, records...)
+	if len(e.pending) >= e.opts.BatchSize {
+		select {
+		case e.ready <- struct{}{}:
+		default:
+		}
+	}
+
+	return len(p), nil
+}
+
+// Dropped returns the number of log records dropped because the queue was full or the export failed
+func (e *OTLPExporter) Dropped() uint64 {
+	return e.dropped.Load()
+}
+
+// Flush exports all queued records
+func (e *OTLPExporter) Flush(ctx context.Context) error {
+	e.exportMu.Lock()
+	defer e.exportMu.Unlock()
+
+	var errs []error
+	for {
+		batch := e.next()
+		if len(batch) == 0 {
+			return errors.Join(errs...)
+		}
+		if err := ctx.Err(); err != nil {
+			e.dropped.Add(uint64(len(batch)))
+			return errors.Join(append(errs, err)...)
+		}
+		errs = append(errs, e.export(ctx, batch))
+	}
+}
+
+// Close exports queued records and stops the exporter
+func (e *OTLPExporter) Close() error {
+	return e.closeContext(context.Background())
+}
+
+// closeContext is Close which stops exporting when ctx is done, the records not exported yet are dropped
+func (e *OTLPExporter) closeContext(ctx context.Context) error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closed = true
+	e.mu.Unlock()
+
+	close(e.stop)
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		e.cancel()
+		<-e.done
+	}
+	defer e.cancel()
+
+	return e.Flush(ctx)
+}
+
+func (e *OTLPExporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+		case <-e.ready:
+		}
+
+		e.exportMu.Lock()
+		if batch := e.next(); len(batch) > 0 {
+			e.export(e.ctx, batch)
+		}
+		e.exportMu.Unlock()
+	}
+}
+
+// next removes and returns the next batch of queued records
+func (e *OTLPExporter) next() []otlpQueuedRecord {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	n := min(len(e.pending), e.opts.BatchSize)
+	batch := e.pending[:n:n]
+	e.pending = e.pending[n:]
+	if len(e.pending) == 0 {
+		e.pending = nil
+	}
+	return batch
+}
+
+// export sends the batch, records of the failed batch are dropped
+func (e *OTLPExporter) export(ctx context.Context, batch []otlpQueuedRecord) error {
+	body := appendOTLPExportRequest(nil, batch)
+
+	ctx, cancel := context.WithTimeout(ctx, e.opts.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		e.dropped.Add(uint64(len(batch)))
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.opts.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.opts.Client.Do(req)
+	if err != nil {
+		e.dropped.Add(uint64(len(batch)))
+		return fmt.Errorf("export OTLP logs: %w", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		e.dropped.Add(uint64(len(batch)))
+		return fmt.Errorf("export OTLP logs: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// appendOTLPExportRequest appends ExportLogsServiceRequest with records grouped by resource and scope
+// in the order of their first records
+func appendOTLPExportRequest(buf []byte, records []otlpQueuedRecord) []byte {
+	type scopeLogs struct {
+		scope   json.RawMessage
+		records []json.RawMessage
+	}
+	type resourceLogs struct {
+		resource json.RawMessage
+		scopes   []*scopeLogs
+		byScope  map[string]*scopeLogs
+	}
+
+	var resources []*resourceLogs
+	byResource := make(map[string]*resourceLogs)
+	for _, r := range records {
+		rl, ok := byResource[string(r.resource)]
+		if !ok {
+			rl = &resourceLogs{resource: r.resource, byScope: make(map[string]*scopeLogs)}
+			byResource[string(r.resource)] = rl
+			resources = append(resources, rl)
+		}
+		sl, ok := rl.byScope[string(r.scope)]
+		if !ok {
+			sl = &scopeLogs{scope: r.scope}
+			rl.byScope[string(r.scope)] = sl
+			rl.scopes = append(rl.scopes, sl)
+		}
+		sl.records = append(sl.records, r.record)
+	}
+
+	appendObject := func(buf []byte, raw json.RawMessage) []byte {
+		if len(raw) == 0 {
+			return append(buf, "{}"...)
+		}
+		return append(buf, raw...)
+	}
+
+	buf = append(buf, `{"resourceLogs":[`...)
+	for i, rl := range resources {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, `{"resource":`...)
+		buf = appendObject(buf, rl.resource)
+		buf = append(buf, `,"scopeLogs":[`...)
+		for j, sl := range rl.scopes {
+			if j > 0 {
+				buf = append(buf, ',')
+			}
+			buf = append(buf, `{"scope":`...)
+			buf = appendObject(buf, sl.scope)
+			buf = append(buf, `,"logRecords":[`...)
+			for k, record := range sl.records {
+				if k > 0 {
+					buf = append(buf, ',')
+				}
+				buf = append(buf, record...)
+			}
+			buf = append(buf, "]}"...)
+		}
+		buf = append(buf, "]}"...)
+	}
+	return append(buf, "]}"...)
+}
@@ -0,0 +1,351 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type otlpKeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano   string         `json:"timeUnixNano"`
+	SeverityNumber int            `json:"severityNumber"`
+	SeverityText   string         `json:"severityText"`
+	Body           map[string]any `json:"body"`
+	Attributes     []otlpKeyValue `json:"attributes"`
+	TraceID        string         `json:"traceId"`
+	SpanID         string         `json:"spanId"`
+	Flags          int            `json:"flags"`
+}
+
+type otlpRequest struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []otlpKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []struct {
+			Scope struct {
+				Name string `json:"name"`
+			} `json:"scope"`
+			LogRecords []otlpLogRecord `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+func decodeOTLPRequest(t *testing.T, data []byte) otlpRequest {
+	t.Helper()
+	var req otlpRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		t.Fatalf("invalid OTLP/JSON %q: %s", data, err.Error())
+	}
+	return req
+}
+
+func (r otlpRequest) records() []otlpLogRecord {
+	var records []otlpLogRecord
+	for _, rl := range r.ResourceLogs {
+		for _, sl := range rl.ScopeLogs {
+			records = append(records, sl.LogRecords...)
+		}
+	}
+	return records
+}
+
+func otlpAttrsJSON(attrs []otlpKeyValue) string {
+	data, _ := json.Marshal(attrs)
+	return string(data)
+}
+
+func TestOTLPSeverityNumber(t *testing.T) {
+	testCases := map[Level]int{
+		LevelDebug - 8: 1,
+		LevelDebug:     5,
+		LevelInfo:      9,
+		LevelInfo + 1:  10,
+		LevelWarn:      13,
+		LevelError:     17,
+		LevelError + 4: 21,
+		LevelError + 8: 24,
+	}
+	for level, expected := range testCases {
+		if n := otlpSeverityNumber(level); n != expected {
+			t.Errorf("expected severity number %d for %s, got %d", expected, level, n)
+		}
+	}
+}
+
+func TestOTLPHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewOTLPHandler(&buf, nil, []Attr{StringAttr(ServiceNameKey, "api"), StringAttr(ServiceVersionKey, "1.2.0")})
+	logger := New(h).With(StringAttr("tenant", "acme")).WithGroup("http").With(StringAttr("method", "GET"))
+
+	tc := TraceContext{
+		TraceID: TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		Flags:   1,
+	}
+	ctx := ContextWithTraceContext(context.Background(), tc)
+	logger.WarnContext(ctx, "slow request", IntAttr("status", 200), DurationAttr("duration", time.Second),
+		Group("client", StringAttr("ip", "10.0.0.1")), AnyAttr("error", errors.New("timeout")), Float64Attr("ratio", 0.5))
+
+	if !bytes.HasSuffix(buf.Bytes(), []byte("}\n")) {
+		t.Fatalf("expected line ended by newline, got %q", buf.String())
+	}
+	req := decodeOTLPRequest(t, buf.Bytes())
+	if len(req.ResourceLogs) != 1 || len(req.ResourceLogs[0].ScopeLogs) != 1 || req.ResourceLogs[0].ScopeLogs[0].Scope.Name != otlpScopeName {
+		t.Fatalf("unexpected request %+v", req)
+	}
+	resource := otlpAttrsJSON(req.ResourceLogs[0].Resource.Attributes)
+	if resource != `[{"key":"service.name","value":{"stringValue":"api"}},{"key":"service.version","value":{"stringValue":"1.2.0"}}]` {
+		t.Errorf("unexpected resource %s", resource)
+	}
+
+	record := req.records()[0]
+	if record.SeverityNumber != 13 || record.SeverityText != "WARN" || record.Body["stringValue"] != "slow request" {
+		t.Errorf("unexpected record %+v", record)
+	}
+	if record.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || record.SpanID != "00f067aa0ba902b7" || record.Flags != 1 {
+		t.Errorf("unexpected trace context %s %s %d", record.TraceID, record.SpanID, record.Flags)
+	}
+	if record.TimeUnixNano == "" {
+		t.Error("expected time")
+	}
+	expected := `[{"key":"tenant","value":{"stringValue":"acme"}},{"key":"http","value":{"kvlistValue":{"values":[` +
+		`{"key":"method","value":{"stringValue":"GET"}},{"key":"status","value":{"intValue":"200"}},` +
+		`{"key":"duration","value":{"intValue":"1000000000"}},` +
+		`{"key":"client","value":{"kvlistValue":{"values":[{"key":"ip","value":{"stringValue":"10.0.0.1"}}]}}},` +
+		`{"key":"error","value":{"stringValue":"timeout"}},{"key":"ratio","value":{"doubleValue":0.5}}]}}}]`
+	if attrs := otlpAttrsJSON(record.Attributes); attrs != expected {
+		t.Errorf("expected attributes %s, got %s", expected, attrs)
+	}
+	buf.Reset()
+
+	New(NewOTLPHandler(&buf, &HandlerOptions{AddSource: true}, nil)).WithGroup("empty").Info("plain")
+	record = decodeOTLPRequest(t, buf.Bytes()).records()[0]
+	if record.TraceID != "" || len(record.Attributes) != 3 || record.Attributes[0].Key != "code.file.path" {
+		t.Errorf("unexpected record without trace context %+v", record)
+	}
+}
+
+func TestInsertGroupAttrs(t *testing.T) {
+	attrs := insertGroupAttrs(nil, []string{"a", "b"}, []Attr{IntAttr("x", 1)})
+	merged := insertGroupAttrs(attrs, []string{"a", "b"}, []Attr{IntAttr("y", 2)})
+	other := insertGroupAttrs(attrs, []string{"a"}, []Attr{IntAttr("z", 3)})
+
+	if s := GroupValue(attrs...).String(); s != "[a=[b=[x=1]]]" {
+		t.Errorf("unexpected attrs %s", s)
+	}
+	if s := GroupValue(merged...).String(); s != "[a=[b=[x=1 y=2]]]" {
+		t.Errorf("unexpected merged attrs %s", s)
+	}
+	if s := GroupValue(other...).String(); s != "[a=[b=[x=1] z=3]]" {
+		t.Errorf("unexpected attrs %s", s)
+	}
+	if s := GroupValue(insertGroupAttrs(attrs, []string{"c"}, nil)...).String(); s != "[a=[b=[x=1]]]" {
+		t.Errorf("expected attrs without empty group, got %s", s)
+	}
+}
+
+type otlpCollector struct {
+	mu       sync.Mutex
+	requests []otlpRequest
+	headers  []http.Header
+	status   int
+}
+
+func (c *otlpCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if r.URL.Path != "/v1/logs" || r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if c.status != 0 {
+		w.WriteHeader(c.status)
+		return
+	}
+	var req otlpRequest
+	json.Unmarshal(body, &req)
+	c.requests = append(c.requests, req)
+	c.headers = append(c.headers, r.Header)
+}
+
+func (c *otlpCollector) records() []otlpLogRecord {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var records []otlpLogRecord
+	for _, req := range c.requests {
+		records = append(records, req.records()...)
+	}
+	return records
+}
+
+func TestOTLPExporter(t *testing.T) {
+	collector := &otlpCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	e, err := NewOTLPExporter(server.URL, OTLPExporterOptions{
+		Headers:   map[string]string{"Authorization": "Bearer token"},
+		BatchSize: 2,
+		Interval:  time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	logger := New(NewOTLPHandler(e, nil, []Attr{StringAttr(ServiceNameKey, "api")}))
+
+	// the full batch is exported in the background
+	logger.Info("first")
+	logger.Info("second")
+	deadline := time.Now().Add(5 * time.Second)
+	for len(collector.records()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(collector.records()) != 2 {
+		t.Fatalf("expected 2 exported records, got %d", len(collector.records()))
+	}
+	collector.mu.Lock()
+	if rl := collector.requests[0].ResourceLogs; len(rl) != 1 || len(rl[0].ScopeLogs) != 1 || len(rl[0].ScopeLogs[0].LogRecords) != 2 {
+		t.Errorf("expected records of the batch in one resource and scope, got %+v", rl)
+	}
+	collector.mu.Unlock()
+
+	logger.Info("third")
+	if err := e.Flush(context.Background()); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+	records := collector.records()
+	if len(records) != 3 || records[2].Body["stringValue"] != "third" {
+		t.Errorf("unexpected records %+v", records)
+	}
+	if collector.headers[0].Get("Authorization") != "Bearer token" {
+		t.Errorf("expected authorization header, got %v", collector.headers[0])
+	}
+
+	collector.mu.Lock()
+	collector.status = http.StatusServiceUnavailable
+	collector.mu.Unlock()
+	logger.Info("fourth")
+	if err := e.Flush(context.Background()); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("expected export error, got %v", err)
+	}
+	if e.Dropped() != 1 {
+		t.Errorf("expected 1 dropped record, got %d", e.Dropped())
+	}
+
+	if err := e.Close(); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+	if _, err := e.Write([]byte(`{"resourceLogs":[]}`)); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("expected os.ErrClosed, got %v", err)
+	}
+}
+
+func TestOTLPExporterQueueFull(t *testing.T) {
+	e, err := NewOTLPExporter("http://127.0.0.1:1/custom/path", OTLPExporterOptions{MaxQueueSize: 1, Interval: time.Hour})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if e.endpoint != "http://127.0.0.1:1/custom/path" {
+		t.Errorf("unexpected endpoint %s", e.endpoint)
+	}
+
+	line := []byte(`{"resourceLogs":[{"resource":{},"scopeLogs":[{"scope":{},"logRecords":[{}]}]}]}` + "\n")
+	if _, err := e.Write(line); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+	if _, err := e.Write(line); !errors.Is(err, errOTLPQueueFull) {
+		t.Errorf("expected queue full error, got %v", err)
+	}
+	if _, err := e.Write([]byte("not json")); err == nil {
+		t.Error("expected error for invalid logs")
+	}
+	e.Close()
+	if e.Dropped() != 2 {
+		t.Errorf("expected 2 dropped records, got %d", e.Dropped())
+	}
+
+	for _, endpoint := range []string{"ftp://collector", "http://", "://"} {
+		if _, err := NewOTLPExporter(endpoint, OTLPExporterOptions{}); err == nil {
+			t.Errorf("expected error for endpoint %s", endpoint)
+		}
+	}
+}
+
+func TestBuildOTLP(t *testing.T) {
+	collector := &otlpCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	logger, handle, err := Build(
+		WithOutputFormat(OutputFormatOTLP),
+		WithOutputFilePath(server.URL),
+		WithService("api", "1.0.0"),
+		WithResource(StringAttr("deployment.environment", "test")),
+		WithSetDefault(false),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	logger.Info("exported")
+	handle.Close()
+
+	collector.mu.Lock()
+	if len(collector.requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(collector.requests))
+	}
+	resource := otlpAttrsJSON(collector.requests[0].ResourceLogs[0].Resource.Attributes)
+	if !strings.Contains(resource, `"service.name"`) || !strings.Contains(resource, `"deployment.environment"`) {
+		t.Errorf("unexpected resource %s", resource)
+	}
+	collector.mu.Unlock()
+
+	config := Config{
+		Format: OutputFormatOTLP,
+		File:   server.URL,
+		OTLP:   &OTLPConfig{Headers: map[string]string{"Authorization": "Bearer token"}, Timeout: Duration(time.Second)},
+	}
+	logger, handle, err = config.Build(WithSetDefault(false))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	logger.Info("exported with headers")
+	handle.Close()
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	if len(collector.headers) != 2 || collector.headers[1].Get("Authorization") != "Bearer token" {
+		t.Errorf("expected authorization header, got %v", collector.headers)
+	}
+
+	path := filepath.Join(t.TempDir(), "otlp.jsonl")
+	config = Config{Format: OutputFormatOTLP, File: path, Resource: map[string]string{ServiceNameKey: "worker"}}
+	logger, handle, err = config.Build(WithSetDefault(false))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	logger.Info("to file")
+	handle.Close()
+
+	data, _ := os.ReadFile(path)
+	req := decodeOTLPRequest(t, data)
+	if otlpAttrsJSON(req.ResourceLogs[0].Resource.Attributes) != `[{"key":"service.name","value":{"stringValue":"worker"}}]` {
+		t.Errorf("unexpected file content %s", data)
+	}
+}
@@ -0,0 +1,311 @@
+package glog
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimit limits records of the logger name at the level, AnyName sets the limit of each name without
+// an own limit. Rate is the number of records per second and Burst is the number of records passed at once,
+// the default burst is the rate rounded up. A zero rate drops all records of the name and level.
+type RateLimit struct {
+	Name  string
+	Level Level
+	Rate  float64
+	Burst int
+}
+
+func (l RateLimit) burst() float64 {
+	if l.Rate == 0 {
+		return 0
+	}
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return max(1, math.Ceil(l.Rate))
+}
+
+type rateLimitKey struct {
+	name  string
+	level Level
+}
+
+const (
+	// rateLimitFlushInterval is the delay of the record with the number of suppressed records if no record
+	// of the name and level passes before
+	rateLimitFlushInterval = 10 * time.Second
+	// rateLimitEvictInterval is the period of removing full buckets, they are recreated on demand
+	rateLimitEvictInterval = time.Minute
+)
+
+// tokenBucket passes records while it has tokens, tokens are added at the rate up to the burst
+type tokenBucket struct {
+	tokens     float64
+	last       time.Time
+	suppressed uint64
+	// next and named are the handler and the origin of the name of the last suppressed record
+	next  Handler
+	named bool
+}
+
+// RateLimits holds token bucket rate limits per logger name and level, the name is taken from the NameKey
+// attribute. It is safe for concurrent use and can be changed at runtime. The number of suppressed records
+// is logged before the next passed record of the name and level, or by a timer, Flush or Close.
+type RateLimits struct {
+	// now is the clock of the buckets, it is replaced in tests
+	now func() time.Time
+
+	mu        sync.Mutex
+	limits    map[rateLimitKey]RateLimit
+	buckets   map[rateLimitKey]*tokenBucket
+	lastEvict time.Time
+	timer     *time.Timer
+	closed    bool
+	count     atomic.Int32
+
+	suppressed atomic.Uint64
+}
+
+func NewRateLimits() *RateLimits {
+	return &RateLimits{
+		now:     time.Now,
+		limits:  make(map[rateLimitKey]RateLimit),
+		buckets: make(map[rateLimitKey]*tokenBucket),
+	}
+}
+
+// Set sets the limit, buckets of the name and level are refilled
+func (rl *RateLimits) Set(limit RateLimit) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.limits[rateLimitKey{name: limit.Name, level: limit.Level}] = limit
+	rl.reset(limit.Name, limit.Level)
+}
+
+// Delete removes the limit of the name and level
+func (rl *RateLimits) Delete(name string, level Level) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	delete(rl.limits, rateLimitKey{name: name, level: level})
+	rl.reset(name, level)
+}
+
+// Limits returns the configured limits
+func (rl *RateLimits) Limits() []RateLimit {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	limits := make([]RateLimit, 0, len(rl.limits))
+	for _, limit := range rl.limits {
+		limits = append(limits, limit)
+	}
+	return limits
+}
+
+// Suppressed returns the number of records dropped by the limits
+func (rl *RateLimits) Suppressed() uint64 {
+	return rl.suppressed.Load()
+}
+
+func (rl *RateLimits) reset(name string, level Level) {
+	rl.count.Store(int32(len(rl.limits)))
+	for key, b := range rl.buckets {
+		if key.level != level || name != AnyName && key.name != name {
+			continue
+		}
+		if b.suppressed > 0 {
+			// the bucket keeps the number of suppressed records and is refilled on the next record
+			b.tokens = math.Inf(1)
+			continue
+		}
+		delete(rl.buckets, key)
+	}
+}
+
+// Flush logs the numbers of records suppressed since the last passed records
+func (rl *RateLimits) Flush(_ context.Context) error {
+	rl.mu.Lock()
+	if rl.timer != nil {
+		rl.timer.Stop()
+		rl.timer = nil
+	}
+	type pending struct {
+		key        rateLimitKey
+		suppressed uint64
+		next       Handler
+		named      bool
+	}
+	var records []pending
+	for key, b := range rl.buckets {
+		if b.suppressed > 0 {
+			records = append(records, pending{key: key, suppressed: b.suppressed, next: b.next, named: b.named})
+			b.suppressed = 0
+			b.next = nil
+		}
+	}
+	now := rl.now()
+	rl.mu.Unlock()
+
+	slices.SortFunc(records, func(a, b pending) int {
+		return cmp.Or(cmp.Compare(a.key.level, b.key.level), cmp.Compare(a.key.name, b.key.name))
+	})
+	var err error
+	for _, p := range records {
+		r := suppressedRecord(now, p.key.level, p.key.name, p.named, p.suppressed)
+		if e := p.next.Handle(context.Background(), r); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Close logs the numbers of suppressed records, later ones are logged only before passed records
+func (rl *RateLimits) Close() error {
+	rl.mu.Lock()
+	rl.closed = true
+	rl.mu.Unlock()
+
+	return rl.Flush(context.Background())
+}
+
+// allow takes a token of the name and level bucket, it returns the number of records suppressed
+// since the last passed record. The handler of a suppressed record logs the number of suppressed
+// records if no record passes before the flush timer.
+func (rl *RateLimits) allow(name string, level Level, next Handler, named bool) (bool, uint64) {
+	if rl.count.Load() == 0 {
+		return true, 0
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	key := rateLimitKey{name: name, level: level}
+	limit, ok := rl.limits[key]
+	if !ok {
+		if limit, ok = rl.limits[rateLimitKey{name: AnyName, level: level}]; !ok {
+			return true, 0
+		}
+	}
+
+	now := rl.now()
+	rl.evict(now)
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: limit.burst(), last: now}
+		rl.buckets[key] = b
+	}
+	b.tokens = min(limit.burst(), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		b.suppressed++
+		b.next, b.named = next, named
+		rl.suppressed.Add(1)
+		if rl.timer == nil && !rl.closed {
+			rl.timer = time.AfterFunc(rateLimitFlushInterval, func() { rl.Flush(context.Background()) })
+		}
+		return false, 0
+	}
+	b.tokens--
+	suppressed := b.suppressed
+	b.suppressed = 0
+	b.next = nil
+
+	return true, suppressed
+}
+
+// evict removes buckets without suppressed records which are refilled, new buckets are full
+func (rl *RateLimits) evict(now time.Time) {
+	if now.Sub(rl.lastEvict) < rateLimitEvictInterval {
+		return
+	}
+	rl.lastEvict = now
+
+	for key, b := range rl.buckets {
+		if b.suppressed > 0 {
+			continue
+		}
+		limit, ok := rl.limits[key]
+		if !ok {
+			limit = rl.limits[rateLimitKey{name: AnyName, level: key.level}]
+		}
+		if b.tokens+now.Sub(b.last).Seconds()*limit.Rate >= limit.burst() {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+func suppressedRecord(t time.Time, level Level, name string, named bool, suppressed uint64) Record {
+	r := NewRecord(t, level, fmt.Sprintf("%d records suppressed", suppressed), 0)
+	if named {
+		r.AddAttrs(StringAttr(NameKey, name))
+	}
+	r.AddAttrs(Uint64Attr("suppressed", suppressed))
+	return r
+}
+
+// RateLimitHandler drops records exceeding the rate limit of the logger name and level. When records pass
+// again, a record with the number of suppressed records is logged before the first one.
+type RateLimitHandler struct {
+	next    Handler
+	limits  *RateLimits
+	name    string
+	grouped bool
+}
+
+func NewRateLimitHandler(next Handler, limits *RateLimits) *RateLimitHandler {
+	return &RateLimitHandler{next: next, limits: limits}
+}
+
+func (h *RateLimitHandler) Enabled(ctx context.Context, level Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *RateLimitHandler) Handle(ctx context.Context, r Record) error {
+	name, recordName := h.name, false
+	if !h.grouped {
+		if n, ok := nameFromRecord(r); ok {
+			name, recordName = n, true
+		}
+	}
+
+	allowed, suppressed := h.limits.allow(name, r.Level, h.next, recordName)
+	if !allowed {
+		return nil
+	}
+	if suppressed > 0 {
+		if err := h.next.Handle(ctx, suppressedRecord(r.Time, r.Level, name, recordName, suppressed)); err != nil {
+			return err
+		}
+	}
+
+	return h.next.Handle(ctx, r)
+}
+
+func (h *RateLimitHandler) WithAttrs(attrs []Attr) Handler {
+	h2 := *h
+	if !h.grouped {
+		if name, ok := nameFromAttrs(attrs); ok {
+			h2.name = name
+		}
+	}
+	h2.next = h.next.WithAttrs(attrs)
+	return &h2
+}
+
+func (h *RateLimitHandler) WithGroup(name string) Handler {
+	h2 := *h
+	if name != "" {
+		h2.grouped = true
+	}
+	h2.next = h.next.WithGroup(name)
+	return &h2
+}
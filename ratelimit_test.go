@@ -0,0 +1,170 @@
+package glog
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitHandler(t *testing.T) {
+	clock, advance := newFakeClock(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	next := newGateHandler()
+	close(next.gate)
+	limits := NewRateLimits()
+	limits.now = clock
+	limits.Set(RateLimit{Name: "http-access", Level: LevelInfo, Rate: 2})
+	logger := New(NewRateLimitHandler(next, limits))
+	access := WithName(logger, "http-access")
+
+	for i := 0; i < 5; i++ {
+		access.Info("request")
+	}
+	access.Warn("not limited")
+	logger.Info("other logger")
+	if len(next.Messages()) != 4 || limits.Suppressed() != 3 {
+		t.Fatalf("expected 4 records and 3 suppressed, got %d and %d", len(next.Messages()), limits.Suppressed())
+	}
+
+	// the bucket gets one token in half a second
+	advance(500 * time.Millisecond)
+	access.Info("request")
+	access.Info("request")
+
+	// the name passed with the record is limited and added to the suppressed record
+	advance(time.Second)
+	limits.Set(RateLimit{Name: AnyName, Level: LevelInfo, Rate: 1, Burst: 1})
+	logger.Info("first", StringAttr(NameKey, "worker"))
+	logger.Info("second", StringAttr(NameKey, "worker"))
+	logger.Info("first", StringAttr(NameKey, "cron"))
+	advance(time.Second)
+	logger.Info("third", StringAttr(NameKey, "worker"))
+
+	expected := []string{
+		"3 records suppressed name=http-access suppressed=3",
+		"request name=http-access",
+		"first name=worker",
+		"first name=cron",
+		"1 records suppressed name=worker suppressed=1",
+		"third name=worker",
+	}
+	if messages := next.Messages()[4:]; !reflect.DeepEqual(messages, expected) {
+		t.Errorf("expected messages %q, got %q", expected, messages)
+	}
+
+	// limits are adjustable at runtime
+	limits.Delete(AnyName, LevelInfo)
+	limits.Delete("http-access", LevelInfo)
+	for i := 0; i < 5; i++ {
+		access.Info("request")
+	}
+	if len(next.Messages()) != 15 || len(limits.Limits()) != 0 {
+		t.Errorf("expected records without limits, got %d", len(next.Messages()))
+	}
+}
+
+func TestRateLimitsZeroRate(t *testing.T) {
+	limits := NewRateLimits()
+	limits.now, _ = newFakeClock(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	limits.Set(RateLimit{Name: "noisy", Level: LevelDebug, Rate: 0, Burst: 5})
+
+	for i := 0; i < 2; i++ {
+		if allowed, _ := limits.allow("noisy", LevelDebug, nil, false); allowed {
+			t.Error("expected records dropped with zero rate")
+		}
+	}
+	if allowed, _ := limits.allow("noisy", LevelInfo, nil, false); !allowed {
+		t.Error("expected records of other levels allowed")
+	}
+}
+
+func TestRateLimitsFlushAndEvict(t *testing.T) {
+	clock, advance := newFakeClock(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	next := newGateHandler()
+	close(next.gate)
+	limits := NewRateLimits()
+	limits.now = clock
+	limits.Set(RateLimit{Name: AnyName, Level: LevelInfo, Rate: 1, Burst: 1})
+	logger := New(NewRateLimitHandler(next, limits))
+
+	// suppressed records are reported by Flush when no record of the name passes
+	for i := 0; i < 3; i++ {
+		WithName(logger, "worker").Info("busy")
+		logger.Info("unnamed", StringAttr(NameKey, "cron"))
+	}
+	if err := limits.Flush(context.Background()); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+	expected := []string{
+		"busy name=worker",
+		"unnamed name=cron",
+		"2 records suppressed name=cron suppressed=2",
+		"2 records suppressed name=worker suppressed=2",
+	}
+	if messages := next.Messages(); !reflect.DeepEqual(messages, expected) {
+		t.Errorf("expected messages %q, got %q", expected, messages)
+	}
+
+	// the counts are not repeated by the next passed record
+	advance(time.Second)
+	WithName(logger, "worker").Info("busy")
+	if messages := next.Messages(); len(messages) != 5 || messages[4] != "busy name=worker" {
+		t.Errorf("unexpected messages %q", next.Messages())
+	}
+
+	// refilled buckets are evicted
+	advance(rateLimitEvictInterval)
+	logger.Info("other", StringAttr(NameKey, "api"))
+	if len(limits.buckets) != 1 {
+		t.Errorf("expected only the new bucket, got %d buckets", len(limits.buckets))
+	}
+
+	WithName(logger, "api").Info("suppressed")
+	limits.Close()
+	if messages := next.Messages(); messages[len(messages)-1] != "1 records suppressed name=api suppressed=1" {
+		t.Errorf("expected suppressed records reported on close, got %q", messages)
+	}
+}
+
+func TestBuildRateLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	var config Config
+	data := `{"file": "` + path + `", "rate_limits": [{"name": "noisy", "level": "info", "rate": 1, "burst": 2}]}`
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	logger, handle, err := config.Build(WithSetDefault(false))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	noisy := WithName(logger, "noisy")
+	for i := 0; i < 5; i++ {
+		noisy.Info("limited")
+	}
+	handle.RateLimits().Set(RateLimit{Name: "noisy", Level: LevelInfo, Rate: 100})
+	noisy.Info("limited")
+	handle.Close()
+
+	output, _ := os.ReadFile(path)
+	if count := strings.Count(string(output), `"msg":"limited"`); count != 3 {
+		t.Errorf("expected 3 records, got %d in %q", count, output)
+	}
+	if handle.RateLimits().Suppressed() != 3 {
+		t.Errorf("expected 3 suppressed records, got %d", handle.RateLimits().Suppressed())
+	}
+
+	if _, _, err := Build(WithRateLimit(RateLimit{Name: "x", Rate: -1}), WithSetDefault(false)); err == nil {
+		t.Error("expected error for negative rate")
+	}
+	if _, _, err := Build(WithRateLimit(RateLimit{Name: "x", Rate: 1}), WithCustomHandler(NewDiscardHandler())); err == nil {
+		t.Error("expected error for custom handler with rate limits")
+	}
+	_, handle, _ = Build(WithSetDefault(false))
+	if handle.RateLimits() == nil || len(handle.RateLimits().Limits()) != 0 {
+		t.Error("expected empty rate limits")
+	}
+}
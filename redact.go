@@ -0,0 +1,268 @@
+package glog
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const defaultRedactionMask = "[REDACTED]"
+
+// RedactionMode defines how redacted values are replaced
+type RedactionMode uint8
+
+const (
+	// RedactMask replaces values with the mask
+	RedactMask RedactionMode = iota
+	// RedactHash replaces values with a salted hash, so equal values can be correlated without being disclosed
+	RedactHash
+)
+
+// ValuePattern finds secrets in string values
+type ValuePattern struct {
+	Regexp *regexp.Regexp
+	// Valid checks the matched text, e.g. the checksum of a card number, nil accepts all matches
+	Valid func(match string) bool
+}
+
+var (
+	// JWTPattern matches JSON Web Tokens
+	JWTPattern = ValuePattern{Regexp: regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]*`)}
+	// CardNumberPattern matches payment card numbers of 13 to 19 digits, optionally separated by spaces
+	// or dashes, with a valid Luhn checksum
+	CardNumberPattern = ValuePattern{Regexp: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), Valid: luhnValid}
+)
+
+// RedactionOptions configures RedactionHandler. Keys, paths and key patterns are matched case-insensitively.
+type RedactionOptions struct {
+	// Keys are attribute keys redacted in any group, e.g. "password" or "authorization"
+	Keys []string
+	// Paths are dotted paths of attributes including groups, e.g. "request.headers.cookie"
+	Paths []string
+	// KeyPatterns are glob patterns of attribute keys in the path.Match syntax, e.g. "*token*"
+	KeyPatterns []string
+	// ValuePatterns find secrets in messages and string values, only the matches are replaced
+	ValuePatterns []ValuePattern
+	// Mode of replacement, the default is RedactMask
+	Mode RedactionMode
+	// Mask replaces values in the RedactMask mode, the default is "[REDACTED]"
+	Mask string
+	// Salt is the key of HMAC-SHA256 hashes in the RedactHash mode
+	Salt []byte
+}
+
+func (o RedactionOptions) validate() error {
+	var errs []error
+	for _, pattern := range o.KeyPatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("invalid redaction key pattern '%s': %w", pattern, err))
+		}
+	}
+	for _, pattern := range o.ValuePatterns {
+		if pattern.Regexp == nil {
+			errs = append(errs, errors.New("redaction value pattern without regexp"))
+		}
+	}
+	if o.Mode == RedactHash && len(o.Salt) == 0 {
+		// unsalted hashes of short values like emails or phone numbers are easily reversed
+		errs = append(errs, errors.New("redaction hash mode requires salt"))
+	}
+	return errors.Join(errs...)
+}
+
+type redactor struct {
+	opts        RedactionOptions
+	keys        map[string]struct{}
+	paths       map[string]struct{}
+	keyPatterns []string
+}
+
+func newRedactor(opts RedactionOptions) *redactor {
+	r := &redactor{
+		opts:  opts,
+		keys:  make(map[string]struct{}, len(opts.Keys)),
+		paths: make(map[string]struct{}, len(opts.Paths)),
+	}
+	if r.opts.Mask == "" {
+		r.opts.Mask = defaultRedactionMask
+	}
+	for _, key := range opts.Keys {
+		r.keys[strings.ToLower(key)] = struct{}{}
+	}
+	for _, p := range opts.Paths {
+		r.paths[strings.ToLower(p)] = struct{}{}
+	}
+	for _, pattern := range opts.KeyPatterns {
+		r.keyPatterns = append(r.keyPatterns, strings.ToLower(pattern))
+	}
+	return r
+}
+
+// matchKey reports whether the attribute with the key in the groups is redacted
+func (r *redactor) matchKey(groups []string, key string) bool {
+	key = strings.ToLower(key)
+	if _, ok := r.keys[key]; ok {
+		return true
+	}
+	if len(r.paths) > 0 {
+		p := key
+		if len(groups) > 0 {
+			p = strings.ToLower(strings.Join(groups, ".")) + "." + key
+		}
+		if _, ok := r.paths[p]; ok {
+			return true
+		}
+	}
+	for _, pattern := range r.keyPatterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *redactor) replace(s string) string {
+	if r.opts.Mode == RedactHash {
+		mac := hmac.New(sha256.New, r.opts.Salt)
+		mac.Write([]byte(s))
+		return "sha256:" + hex.EncodeToString(mac.Sum(nil)[:8])
+	}
+	return r.opts.Mask
+}
+
+// redactString replaces matches of the value patterns
+func (r *redactor) redactString(s string) string {
+	for _, pattern := range r.opts.ValuePatterns {
+		s = pattern.Regexp.ReplaceAllStringFunc(s, func(match string) string {
+			if pattern.Valid != nil && !pattern.Valid(match) {
+				return match
+			}
+			return r.replace(match)
+		})
+	}
+	return s
+}
+
+// attr returns the redacted attribute, values of LogValuers are resolved before redaction
+func (r *redactor) attr(groups []string, a Attr) Attr {
+	a.Value = a.Value.Resolve()
+	if a.Key != "" && r.matchKey(groups, a.Key) {
+		a.Value = StringValue(r.replace(a.Value.String()))
+		return a
+	}
+
+	switch a.Value.Kind() {
+	case KindGroup:
+		if a.Key != "" {
+			groups = append(groups[:len(groups):len(groups)], a.Key)
+		}
+		members := a.Value.Group()
+		redacted := make([]Attr, len(members))
+		for i, member := range members {
+			redacted[i] = r.attr(groups, member)
+		}
+		a.Value = GroupValue(redacted...)
+	case KindString:
+		if len(r.opts.ValuePatterns) > 0 {
+			a.Value = StringValue(r.redactString(a.Value.String()))
+		}
+	case KindAny:
+		// e.g. errors with secrets in the message
+		if len(r.opts.ValuePatterns) > 0 {
+			if s := a.Value.String(); r.redactString(s) != s {
+				a.Value = StringValue(r.redactString(s))
+			}
+		}
+	}
+	return a
+}
+
+// RedactionHandler replaces secrets in records before they are passed to the next handler: values of
+// attributes matched by keys, paths or key patterns are replaced as a whole, matches of value patterns
+// are replaced in messages and string values. Values of LogValuers are resolved and redacted as well,
+// e.g. the auth attribute of the access log middleware.
+type RedactionHandler struct {
+	next   Handler
+	r      *redactor
+	groups []string
+}
+
+// NewRedactionHandler creates handler redacting records passed to next, it returns an error for invalid
+// key patterns, value patterns without regexp and the RedactHash mode without salt
+func NewRedactionHandler(next Handler, opts RedactionOptions) (*RedactionHandler, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	return &RedactionHandler{next: next, r: newRedactor(opts)}, nil
+}
+
+func (h *RedactionHandler) Enabled(ctx context.Context, level Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *RedactionHandler) Handle(ctx context.Context, r Record) error {
+	message := r.Message
+	if len(h.r.opts.ValuePatterns) > 0 {
+		message = h.r.redactString(message)
+	}
+
+	redacted := NewRecord(r.Time, r.Level, message, r.PC)
+	r.Attrs(func(attr Attr) bool {
+		redacted.AddAttrs(h.r.attr(h.groups, attr))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h *RedactionHandler) WithAttrs(attrs []Attr) Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	redacted := make([]Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = h.r.attr(h.groups, attr)
+	}
+	h2 := *h
+	h2.next = h.next.WithAttrs(redacted)
+	return &h2
+}
+
+func (h *RedactionHandler) WithGroup(name string) Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	h2.next = h.next.WithGroup(name)
+	return &h2
+}
+
+// luhnValid reports whether the digits of the card number have a valid Luhn checksum
+func luhnValid(number string) bool {
+	sum, digits := 0, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		if digits%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits >= 13 && digits <= 19 && sum%10 == 0
+}
@@ -0,0 +1,139 @@
+package glog
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// ReopenableFile is an io.WriteCloser writing to a file which can be closed and opened again
+// by path, e.g. after an external tool like logrotate has renamed it. Writes and reopening
+// are serialized, so records written concurrently are never dropped or interleaved.
+type ReopenableFile struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+
+	sigs chan os.Signal
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewReopenableFile opens or creates path for appending
+func NewReopenableFile(path string) (*ReopenableFile, error) {
+	f, err := openLogFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReopenableFile{path: path, file: f}, nil
+}
+
+func openLogFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+}
+
+func (f *ReopenableFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	return f.file.Write(p)
+}
+
+// Reopen closes the current file and opens the file by path again. If the file
+// can't be opened, writing continues to the previously opened one.
+func (f *ReopenableFile) Reopen() error {
+	file, err := openLogFile(f.path)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		file.Close()
+		return os.ErrClosed
+	}
+
+	old := f.file
+	f.file = file
+
+	return old.Close()
+}
+
+// ReopenOnSignal starts reopening the file every time the process receives one of signals,
+// SIGHUP is used if no signals are passed. Signal handling stops when the file is closed.
+func (f *ReopenableFile) ReopenOnSignal(signals ...os.Signal) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.sigs != nil || f.file == nil {
+		return
+	}
+
+	f.sigs = make(chan os.Signal, 1)
+	f.done = make(chan struct{})
+	signal.Notify(f.sigs, signals...)
+
+	f.wg.Add(1)
+	go f.watchSignals(f.sigs, f.done)
+}
+
+func (f *ReopenableFile) watchSignals(sigs <-chan os.Signal, done <-chan struct{}) {
+	defer f.wg.Done()
+	for {
+		select {
+		case <-done:
+			return
+		case <-sigs:
+			if err := f.Reopen(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error on reopening logging file: %s\n", err.Error())
+			}
+		}
+	}
+}
+
+// Sync commits the current contents of the file to stable storage
+func (f *ReopenableFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return os.ErrClosed
+	}
+
+	return f.file.Sync()
+}
+
+// Close stops signal handling and closes the file
+func (f *ReopenableFile) Close() error {
+	f.mu.Lock()
+	if f.sigs != nil {
+		signal.Stop(f.sigs)
+		close(f.done)
+		f.sigs = nil
+	}
+	file := f.file
+	f.file = nil
+	f.mu.Unlock()
+
+	f.wg.Wait()
+
+	if file == nil {
+		return nil
+	}
+
+	return file.Close()
+}
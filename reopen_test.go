@@ -0,0 +1,141 @@
+package glog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReopenableFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	rotated := filepath.Join(dir, "app.log.1")
+
+	f, err := NewReopenableFile(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	defer f.Close()
+
+	f.Write([]byte("before\n"))
+	if err := os.Rename(path, rotated); err != nil {
+		t.Fatalf("rename error: %s", err.Error())
+	}
+	f.Write([]byte("renamed\n"))
+
+	if err := f.Reopen(); err != nil {
+		t.Fatalf("reopen error: %s", err.Error())
+	}
+	f.Write([]byte("after\n"))
+
+	data, _ := os.ReadFile(rotated)
+	if string(data) != "before\nrenamed\n" {
+		t.Errorf("unexpected rotated file content %q", data)
+	}
+	data, _ = os.ReadFile(path)
+	if string(data) != "after\n" {
+		t.Errorf("unexpected new file content %q", data)
+	}
+
+	// failed reopen keeps the previous file
+	f.path = filepath.Join(dir, "missing", "app.log")
+	if err := f.Reopen(); err == nil {
+		t.Error("expected reopen error")
+	}
+	if _, err := f.Write([]byte("still written\n")); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+}
+
+func TestReopenableFileSignal(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	rotated := filepath.Join(dir, "app.log.1")
+
+	file, err := NewReopenableFile(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	file.ReopenOnSignal()
+	file.ReopenOnSignal()
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				file.Write([]byte("record\n"))
+			}
+		}
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	if err := os.Rename(path, rotated); err != nil {
+		t.Fatalf("rename error: %s", err.Error())
+	}
+	file.sigs <- syscall.SIGHUP
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(stop)
+	wg.Wait()
+
+	if err := file.Close(); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+	if _, err := file.Write([]byte("x")); err != os.ErrClosed {
+		t.Errorf("expected os.ErrClosed, got %v", err)
+	}
+	if err := file.Reopen(); err != os.ErrClosed {
+		t.Errorf("expected os.ErrClosed, got %v", err)
+	}
+
+	for _, name := range []string{path, rotated} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("read file error: %s", err.Error())
+		}
+		if len(data) == 0 {
+			t.Errorf("expected records in %s", name)
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			if line != "record" {
+				t.Errorf("broken record %q in %s", line, name)
+			}
+		}
+	}
+}
+
+func TestNewLoggerReopenOnSIGHUP(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	logger := NewLogger(
+		WithOutputFilePath(path),
+		WithReopenOnSIGHUP(true),
+		WithSetDefault(false),
+		WithAddSource(false),
+	)
+	logger.Info("test")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read file error: %s", err.Error())
+	}
+	if !strings.Contains(string(data), `"msg":"test"`) {
+		t.Errorf("wrong logs output data %q", data)
+	}
+}
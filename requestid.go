@@ -0,0 +1,68 @@
+package glog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+)
+
+const (
+	RequestIDKey           = "request_id"
+	DefaultRequestIDHeader = "X-Request-ID"
+	// maxRequestIDLength limits incoming request IDs, longer ones are replaced by generated IDs
+	maxRequestIDLength = 128
+)
+
+type requestIDContextKey struct{}
+
+// ContextWithRequestID puts request ID to context
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// RequestIDFromContext returns request ID from context
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDContextKey{}).(string)
+	return id, ok
+}
+
+// NewRequestID returns a random UUIDv7, IDs generated later sort after earlier ones
+func NewRequestID() string {
+	var uuid [16]byte
+	_, _ = rand.Read(uuid[6:])
+
+	// 48 bits of unix milliseconds, the version and the variant
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(time.Now().UnixMilli()))
+	copy(uuid[:6], ms[2:])
+	uuid[6] = uuid[6]&0x0f | 0x70
+	uuid[8] = uuid[8]&0x3f | 0x80
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], uuid[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], uuid[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], uuid[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], uuid[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], uuid[10:])
+	return string(buf[:])
+}
+
+// validRequestID reports whether incoming request ID can be logged and echoed: it is not empty, not too long
+// and has only printable ASCII characters
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
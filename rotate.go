@@ -0,0 +1,378 @@
+package glog
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+	compressSuffix   = ".gz"
+)
+
+// RotationOptions configures log file rotation
+type RotationOptions struct {
+	// MaxSize is the maximum size in bytes of the log file before it gets rotated, 0 disables size based rotation
+	MaxSize int64
+	// MaxAge is the maximum time to retain rotated files, 0 keeps them regardless of age
+	MaxAge time.Duration
+	// MaxBackups is the maximum number of rotated files to retain, 0 keeps all of them
+	MaxBackups int
+	// Compress enables gzip compression of rotated files
+	Compress bool
+	// LocalTime uses local time instead of UTC in backup file names
+	LocalTime bool
+}
+
+func (o RotationOptions) enabled() bool {
+	return o.MaxSize > 0 || o.MaxAge > 0 || o.MaxBackups > 0
+}
+
+// RotatingWriter is an io.WriteCloser writing to a file which is rotated when it reaches
+// the configured size. Rotated files are renamed to name-<timestamp>.ext, or name-<timestamp>-<n>.ext
+// when the name is taken, and old ones are removed according to MaxAge and MaxBackups and compressed
+// in the background. It is safe for concurrent use.
+type RotatingWriter struct {
+	filename string
+	opts     RotationOptions
+	// now is the clock of backup names and MaxAge, it is replaced in tests
+	now func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	closed bool
+
+	// cleanupCh requests cleanup of backups by the goroutine which closes cleanupDone when it exits
+	cleanupCh   chan struct{}
+	cleanupDone chan struct{}
+	cleanupErr  error
+}
+
+// NewRotatingWriter opens or creates filename for appending and returns a rotating writer on top of it
+func NewRotatingWriter(filename string, opts RotationOptions) (*RotatingWriter, error) {
+	w := &RotatingWriter{filename: filename, opts: opts, now: time.Now}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	if err := w.cleanup(); err != nil {
+		w.file.Close()
+		return nil, err
+	}
+	if w.cleanupEnabled() {
+		w.cleanupCh = make(chan struct{}, 1)
+		w.cleanupDone = make(chan struct{})
+		go w.runCleanup()
+	}
+	return w, nil
+}
+
+// Write writes to the log file rotating it if it reaches the max size. When the rotation fails,
+// p is written to the current file and the error is returned.
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.ensureOpen(); err != nil {
+		return 0, err
+	}
+
+	var rotateErr error
+	if w.opts.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.opts.MaxSize {
+		if rotateErr = w.rotate(); w.file == nil {
+			return 0, rotateErr
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+
+	return n, errors.Join(rotateErr, err)
+}
+
+// Rotate forces rotation of the current log file
+func (w *RotatingWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.ensureOpen(); err != nil {
+		return err
+	}
+
+	return w.rotate()
+}
+
+// Sync commits the current contents of the log file to stable storage
+func (w *RotatingWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.ensureOpen(); err != nil {
+		return err
+	}
+
+	return w.file.Sync()
+}
+
+// Close closes the current log file and waits for the background cleanup, errors of the cleanup
+// are returned
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	if w.cleanupCh != nil {
+		close(w.cleanupCh)
+	}
+	w.mu.Unlock()
+
+	if w.cleanupDone != nil {
+		<-w.cleanupDone
+	}
+
+	return errors.Join(err, w.cleanupErr)
+}
+
+// ensureOpen reopens the log file if it could not be reopened after a failed rotation
+func (w *RotatingWriter) ensureOpen() error {
+	if w.closed {
+		return os.ErrClosed
+	}
+	if w.file == nil {
+		return w.open()
+	}
+	return nil
+}
+
+func (w *RotatingWriter) open() error {
+	f, err := openLogFile(w.filename)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	w.file = f
+	w.size = info.Size()
+
+	return nil
+}
+
+// rotate renames the log file to a backup and opens a new one, the current file is reopened
+// when the rename fails
+func (w *RotatingWriter) rotate() error {
+	closeErr := w.file.Close()
+	w.file = nil
+
+	if err := os.Rename(w.filename, w.backupName(w.now())); err != nil {
+		return errors.Join(fmt.Errorf("rotate log file: %w", err), closeErr, w.open())
+	}
+
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	if w.cleanupCh != nil {
+		select {
+		case w.cleanupCh <- struct{}{}:
+		default:
+		}
+	}
+
+	return closeErr
+}
+
+// backupName returns the name of a backup created at t which is not taken by an existing backup
+func (w *RotatingWriter) backupName(t time.Time) string {
+	if !w.opts.LocalTime {
+		t = t.UTC()
+	}
+	dir, prefix, ext := w.nameParts()
+	name := prefix + t.Format(backupTimeFormat)
+
+	path := filepath.Join(dir, name+ext)
+	for seq := 1; backupExists(path); seq++ {
+		path = filepath.Join(dir, name+"-"+strconv.Itoa(seq)+ext)
+	}
+
+	return path
+}
+
+func backupExists(path string) bool {
+	for _, name := range []string{path, path + compressSuffix} {
+		if _, err := os.Lstat(name); !os.IsNotExist(err) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *RotatingWriter) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(w.filename)
+	base := filepath.Base(w.filename)
+	ext = filepath.Ext(base)
+	prefix = base[:len(base)-len(ext)] + "-"
+
+	return dir, prefix, ext
+}
+
+type backupFile struct {
+	path       string
+	timestamp  time.Time
+	seq        int
+	compressed bool
+}
+
+// backups returns rotated files of the writer sorted from newest to oldest
+func (w *RotatingWriter) backups() ([]backupFile, error) {
+	dir, prefix, ext := w.nameParts()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []backupFile
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		compressed := strings.HasSuffix(name, compressSuffix)
+		name = strings.TrimSuffix(name, compressSuffix)
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		ts, seq := name[len(prefix):len(name)-len(ext)], 0
+		if len(ts) > len(backupTimeFormat) && ts[len(backupTimeFormat)] == '-' {
+			n, err := strconv.Atoi(ts[len(backupTimeFormat)+1:])
+			if err != nil || n <= 0 {
+				continue
+			}
+			ts, seq = ts[:len(backupTimeFormat)], n
+		}
+		t, err := time.ParseInLocation(backupTimeFormat, ts, w.location())
+		if err != nil {
+			continue
+		}
+		files = append(files, backupFile{path: filepath.Join(dir, e.Name()), timestamp: t, seq: seq, compressed: compressed})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].timestamp.Equal(files[j].timestamp) {
+			return files[i].seq > files[j].seq
+		}
+		return files[i].timestamp.After(files[j].timestamp)
+	})
+
+	return files, nil
+}
+
+func (w *RotatingWriter) location() *time.Location {
+	if w.opts.LocalTime {
+		return time.Local
+	}
+	return time.UTC
+}
+
+func (w *RotatingWriter) cleanupEnabled() bool {
+	return w.opts.MaxBackups > 0 || w.opts.MaxAge > 0 || w.opts.Compress
+}
+
+// runCleanup cleans up backups after rotations until the writer is closed, the last error is kept
+// for Close
+func (w *RotatingWriter) runCleanup() {
+	defer close(w.cleanupDone)
+	for range w.cleanupCh {
+		if err := w.cleanup(); err != nil {
+			w.cleanupErr = err
+		}
+	}
+}
+
+// cleanup removes backups exceeding MaxBackups or older than MaxAge and compresses the remaining ones
+func (w *RotatingWriter) cleanup() error {
+	if !w.cleanupEnabled() {
+		return nil
+	}
+
+	files, err := w.backups()
+	if err != nil {
+		return err
+	}
+
+	var cutoff time.Time
+	if w.opts.MaxAge > 0 {
+		cutoff = w.now().Add(-w.opts.MaxAge)
+	}
+
+	for i, f := range files {
+		expired := w.opts.MaxBackups > 0 && i >= w.opts.MaxBackups
+		expired = expired || (!cutoff.IsZero() && f.timestamp.Before(cutoff))
+		if expired {
+			if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		if w.opts.Compress && !f.compressed {
+			if err := compressFile(f.path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+compressSuffix, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	src.Close()
+
+	return os.Remove(path)
+}
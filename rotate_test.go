@@ -0,0 +1,295 @@
+package glog
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newFakeClock returns a clock starting at start which is moved forward by advance
+func newFakeClock(start time.Time) (clock func() time.Time, advance func(d time.Duration)) {
+	now := start
+	var mu sync.Mutex
+	clock = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	advance = func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+	return clock, advance
+}
+
+func listDir(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir error: %s", err.Error())
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+// waitDir waits for the background cleanup to leave n files in dir and returns the files
+func waitDir(t *testing.T, dir string, n int) []string {
+	deadline := time.Now().Add(5 * time.Second)
+	names := listDir(t, dir)
+	for len(names) != n && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		names = listDir(t, dir)
+	}
+	return names
+}
+
+func TestRotatingWriterMaxSize(t *testing.T) {
+	clock, advance := newFakeClock(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	w, err := NewRotatingWriter(path, RotationOptions{MaxSize: 10})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	defer w.Close()
+	w.now = clock
+
+	w.Write([]byte("12345678\n"))
+	advance(time.Second)
+	w.Write([]byte("abcdefgh\n"))
+
+	names := listDir(t, dir)
+	if len(names) != 2 {
+		t.Fatalf("expected 2 files, got %v", names)
+	}
+
+	backup := filepath.Join(dir, "app-2024-05-01T10-00-01.000.log")
+	data, err := os.ReadFile(backup)
+	if err != nil {
+		t.Fatalf("expected backup file %s: %s", backup, err.Error())
+	}
+	if string(data) != "12345678\n" {
+		t.Errorf("unexpected backup content %q", data)
+	}
+
+	data, _ = os.ReadFile(path)
+	if string(data) != "abcdefgh\n" {
+		t.Errorf("unexpected current file content %q", data)
+	}
+}
+
+func TestRotatingWriterMaxBackupsAndAge(t *testing.T) {
+	clock, advance := newFakeClock(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	w, err := NewRotatingWriter(path, RotationOptions{MaxBackups: 2, MaxAge: time.Hour})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	defer w.Close()
+	w.now = clock
+
+	for i := 0; i < 4; i++ {
+		w.Write([]byte("line\n"))
+		advance(time.Minute)
+		if err := w.Rotate(); err != nil {
+			t.Fatalf("rotate error: %s", err.Error())
+		}
+	}
+
+	if names := waitDir(t, dir, 3); len(names) != 3 {
+		t.Errorf("expected current file and 2 backups, got %v", names)
+	}
+
+	advance(2 * time.Hour)
+	w.Write([]byte("line\n"))
+	if err := w.Rotate(); err != nil {
+		t.Fatalf("rotate error: %s", err.Error())
+	}
+
+	names := waitDir(t, dir, 2)
+	if len(names) != 2 {
+		t.Fatalf("expected current file and 1 backup, got %v", names)
+	}
+	if names[0] != "app-2024-05-01T12-04-00.000.log" {
+		t.Errorf("unexpected backup file %s", names[0])
+	}
+}
+
+func TestRotatingWriterCompressLocalTime(t *testing.T) {
+	loc := time.FixedZone("test", 3*60*60)
+	clock, _ := newFakeClock(time.Date(2024, 5, 1, 10, 0, 0, 0, loc))
+	local := time.Local
+	time.Local = loc
+	defer func() { time.Local = local }()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	w, err := NewRotatingWriter(path, RotationOptions{MaxSize: 100, Compress: true, LocalTime: true})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	w.now = clock
+
+	w.Write([]byte("compressed line\n"))
+	if err := w.Rotate(); err != nil {
+		t.Fatalf("rotate error: %s", err.Error())
+	}
+	// backups are compressed in the background, Close waits for it
+	if err := w.Close(); err != nil {
+		t.Fatalf("close error: %s", err.Error())
+	}
+
+	f, err := os.Open(filepath.Join(dir, "app-2024-05-01T10-00-00.000.log.gz"))
+	if err != nil {
+		t.Fatalf("expected compressed backup: %s, files %v", err.Error(), listDir(t, dir))
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip reader error: %s", err.Error())
+	}
+	data, _ := io.ReadAll(gz)
+	if string(data) != "compressed line\n" {
+		t.Errorf("unexpected backup content %q", data)
+	}
+	if names := listDir(t, dir); len(names) != 2 {
+		t.Errorf("expected uncompressed backup to be removed, got %v", names)
+	}
+}
+
+func TestRotatingWriterConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	// rotations within the same millisecond get backups with sequence numbers
+	logger := NewLogger(
+		WithOutputFilePath(path),
+		WithMaxSize(4096),
+		WithSetDefault(false),
+		WithAddSource(false),
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logger.Info("concurrent record", IntAttr("j", j))
+			}
+		}()
+	}
+	wg.Wait()
+
+	lines := 0
+	for _, name := range listDir(t, dir) {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("read file error: %s", err.Error())
+		}
+		if len(data) > 4096 {
+			t.Errorf("file %s exceeds max size: %d", name, len(data))
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			if !strings.HasSuffix(line, "}") {
+				t.Errorf("broken log line %q", line)
+			}
+			lines++
+		}
+	}
+	if lines != 800 {
+		t.Errorf("expected 800 log lines, got %d", lines)
+	}
+}
+
+func TestRotatingWriterSameTime(t *testing.T) {
+	clock, _ := newFakeClock(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	w, err := NewRotatingWriter(path, RotationOptions{MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	w.now = clock
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		w.Write([]byte(line))
+		if err := w.Rotate(); err != nil {
+			t.Fatalf("rotate error: %s", err.Error())
+		}
+	}
+	w.Close()
+
+	// the oldest backup is removed
+	names := listDir(t, dir)
+	expected := []string{"app-2024-05-01T10-00-00.000-1.log", "app-2024-05-01T10-00-00.000-2.log", "app.log"}
+	if strings.Join(names, " ") != strings.Join(expected, " ") {
+		t.Fatalf("expected files %v, got %v", expected, names)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, expected[1])); string(data) != "third\n" {
+		t.Errorf("unexpected backup content %q", data)
+	}
+}
+
+func TestRotatingWriterRotateError(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	w, err := NewRotatingWriter(path, RotationOptions{MaxSize: 10})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	defer w.Close()
+
+	// the log file is removed and can not be renamed, the writer keeps writing to a new file
+	w.Write([]byte("12345678\n"))
+	os.Remove(path)
+	if n, err := w.Write([]byte("abcdefgh\n")); err == nil || n != 9 {
+		t.Errorf("expected rotation error and 9 bytes written, got %d, %v", n, err)
+	}
+	os.Remove(path)
+	if err := w.Rotate(); err == nil {
+		t.Error("expected rotation error")
+	}
+	if _, err := w.Write([]byte("x\n")); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "x\n" {
+		t.Errorf("unexpected file content %q", data)
+	}
+}
+
+func TestRotatingWriterClosed(t *testing.T) {
+	w, err := NewRotatingWriter(filepath.Join(t.TempDir(), "app.log"), RotationOptions{MaxSize: 10})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+	if _, err := w.Write([]byte("x")); err != os.ErrClosed {
+		t.Errorf("expected os.ErrClosed, got %v", err)
+	}
+	if err := w.Rotate(); err != os.ErrClosed {
+		t.Errorf("expected os.ErrClosed, got %v", err)
+	}
+
+	if _, err := NewRotatingWriter(filepath.Join(t.TempDir(), "missing", "app.log"), RotationOptions{}); err == nil {
+		t.Error("expected error for missing directory")
+	}
+}
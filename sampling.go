@@ -0,0 +1,181 @@
+package glog
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultSamplingInterval = time.Second
+
+// SamplingOptions configures SamplingHandler
+type SamplingOptions struct {
+	// Interval is the period counters of records are reset at, the default is one second
+	Interval time.Duration
+	// First is the number of records with the same level and message passed in each interval
+	First int
+	// Thereafter is the period of passed records after the first ones, e.g. 10 passes every 10th record,
+	// zero drops all records after the first ones
+	Thereafter int
+}
+
+// SamplingHandler caps the number of records with the same level and message: the first records in each
+// interval are passed to the next handler and then every Mth. A summary record with the number of sampled
+// out records is logged at the end of the interval they were dropped in. Handlers derived by WithAttrs and
+// WithGroup share the counters.
+type SamplingHandler struct {
+	next Handler
+	s    *samplingState
+}
+
+type samplingKey struct {
+	level   Level
+	message string
+}
+
+type samplingState struct {
+	opts SamplingOptions
+	// root receives summary records
+	root Handler
+	// now is the clock of intervals, it is replaced in tests
+	now func() time.Time
+
+	mu       sync.Mutex
+	start    time.Time
+	counters map[samplingKey]int
+	dropped  map[samplingKey]uint64
+	timer    *time.Timer
+	closed   bool
+
+	sampled atomic.Uint64
+}
+
+// NewSamplingHandler creates sampling handler passing records to next
+func NewSamplingHandler(next Handler, opts SamplingOptions) *SamplingHandler {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultSamplingInterval
+	}
+
+	return &SamplingHandler{
+		next: next,
+		s: &samplingState{
+			opts:     opts,
+			root:     next,
+			now:      time.Now,
+			counters: make(map[samplingKey]int),
+			dropped:  make(map[samplingKey]uint64),
+		},
+	}
+}
+
+func (h *SamplingHandler) Enabled(ctx context.Context, level Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *SamplingHandler) Handle(ctx context.Context, r Record) error {
+	if !h.s.sample(samplingKey{level: r.Level, message: r.Message}) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *SamplingHandler) WithAttrs(attrs []Attr) Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return &SamplingHandler{next: h.next.WithAttrs(attrs), s: h.s}
+}
+
+func (h *SamplingHandler) WithGroup(name string) Handler {
+	if name == "" {
+		return h
+	}
+	return &SamplingHandler{next: h.next.WithGroup(name), s: h.s}
+}
+
+// Sampled returns the number of records sampled out
+func (h *SamplingHandler) Sampled() uint64 {
+	return h.s.sampled.Load()
+}
+
+// Flush logs the summary of records sampled out since the last summary
+func (h *SamplingHandler) Flush(_ context.Context) error {
+	return h.s.summarize()
+}
+
+// Close logs the last summary, records sampled out after Close are only counted
+func (h *SamplingHandler) Close() error {
+	h.s.mu.Lock()
+	h.s.closed = true
+	if h.s.timer != nil {
+		h.s.timer.Stop()
+	}
+	h.s.mu.Unlock()
+
+	return h.s.summarize()
+}
+
+// sample counts the record and reports whether it is passed
+func (s *samplingState) sample(key samplingKey) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.start) >= s.opts.Interval {
+		s.start = now
+		clear(s.counters)
+	}
+
+	s.counters[key]++
+	n := s.counters[key] - s.opts.First
+	if n <= 0 || s.opts.Thereafter > 0 && n%s.opts.Thereafter == 0 {
+		return true
+	}
+
+	s.sampled.Add(1)
+	s.dropped[key]++
+	if s.timer == nil && !s.closed {
+		// the summary is logged at the end of the interval
+		s.timer = time.AfterFunc(s.opts.Interval-now.Sub(s.start), func() { s.summarize() })
+	}
+
+	return false
+}
+
+// summarize logs a record for each level and message with the number of records sampled out
+func (s *samplingState) summarize() error {
+	s.mu.Lock()
+	dropped := s.dropped
+	s.dropped = make(map[samplingKey]uint64)
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.mu.Unlock()
+
+	keys := make([]samplingKey, 0, len(dropped))
+	for key := range dropped {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b samplingKey) int {
+		return cmp.Or(cmp.Compare(a.level, b.level), cmp.Compare(a.message, b.message))
+	})
+
+	ctx := context.Background()
+	var err error
+	for _, key := range keys {
+		if !s.root.Enabled(ctx, key.level) {
+			continue
+		}
+		r := NewRecord(s.now(), key.level, "Log records sampled out", 0)
+		r.AddAttrs(StringAttr("message", key.message), Uint64Attr("count", dropped[key]))
+		if e := s.root.Handle(ctx, r); e != nil {
+			err = e
+		}
+	}
+
+	return err
+}
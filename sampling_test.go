@@ -0,0 +1,119 @@
+package glog
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSamplingHandler(t *testing.T) {
+	clock, advance := newFakeClock(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	var records []Record
+	h := NewSamplingHandler(NewRecordsHandler(&records), SamplingOptions{Interval: time.Hour, First: 2, Thereafter: 3})
+	h.s.now = clock
+	logger := New(h)
+
+	for i := 0; i < 10; i++ {
+		logger.Info("hot loop", IntAttr("i", i))
+	}
+	logger.Warn("hot loop")
+	WithName(logger, "other").Info("other message")
+
+	// records 1, 2, 5 and 8 of the hot loop are passed
+	var passed []int64
+	for _, r := range records {
+		if r.Message == "hot loop" && r.Level == LevelInfo {
+			r.Attrs(func(attr Attr) bool {
+				passed = append(passed, attr.Value.Int64())
+				return true
+			})
+		}
+	}
+	if len(records) != 6 || len(passed) != 4 || passed[2] != 4 || passed[3] != 7 {
+		t.Errorf("unexpected passed records %d, hot loop %v", len(records), passed)
+	}
+	if h.Sampled() != 6 {
+		t.Errorf("expected 6 sampled records, got %d", h.Sampled())
+	}
+
+	// counters are reset in the next interval
+	advance(time.Hour)
+	records = nil
+	logger.Info("hot loop")
+	logger.Info("hot loop")
+	if len(records) != 2 {
+		t.Errorf("expected counters reset, got %d records", len(records))
+	}
+
+	records = nil
+	if err := h.Flush(context.Background()); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 summary record, got %d", len(records))
+	}
+	if err := checkLogRecord(records[0], LevelInfo, "Log records sampled out", []Attr{
+		StringAttr("message", "hot loop"),
+		Uint64Attr("count", 6),
+	}); err != nil {
+		t.Error(err.Error())
+	}
+
+	records = nil
+	h.Flush(context.Background())
+	h.Close()
+	if len(records) != 0 {
+		t.Errorf("expected no summary without sampled records, got %d", len(records))
+	}
+}
+
+func TestSamplingHandlerSummaryTimer(t *testing.T) {
+	next := newGateHandler()
+	close(next.gate)
+	h := NewSamplingHandler(next, SamplingOptions{Interval: 20 * time.Millisecond, First: 1})
+	defer h.Close()
+	logger := New(h).With(StringAttr("service", "api"))
+
+	logger.Warn("retry")
+	logger.Warn("retry")
+	logger.Warn("retry")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(next.Messages()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	messages := next.Messages()
+	if len(messages) != 2 || messages[0] != "retry service=api" || messages[1] != "Log records sampled out message=retry count=2" {
+		t.Errorf("unexpected messages %v", messages)
+	}
+}
+
+func TestBuildSampling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	var config Config
+	if err := json.Unmarshal([]byte(`{"file": "`+path+`", "sampling": {"interval": "1h", "first": 1, "thereafter": 0}}`), &config); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	logger, handle, err := config.Build(WithSetDefault(false))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+
+	for i := 0; i < 5; i++ {
+		logger.Info("sampled")
+	}
+	handle.Close()
+
+	data, _ := os.ReadFile(path)
+	if strings.Count(string(data), `"msg":"sampled"`) != 1 || !strings.Contains(string(data), `"msg":"Log records sampled out","message":"sampled","count":4`) {
+		t.Errorf("unexpected file content %q", data)
+	}
+
+	if _, _, err := Build(WithSampling(SamplingOptions{}), WithSetDefault(false)); err == nil {
+		t.Error("expected error for sampling without first records")
+	}
+}
@@ -0,0 +1,589 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// syslogSDID is the structured data element of attributes, 32473 is the private enterprise number
+	// reserved for documentation by RFC 5612
+	syslogSDID = "glog@32473"
+
+	syslogQueueSize    = 1024
+	syslogDialTimeout  = 5 * time.Second
+	syslogWriteTimeout = 10 * time.Second
+	syslogMinBackoff   = 100 * time.Millisecond
+	syslogMaxBackoff   = 5 * time.Second
+)
+
+// syslogLocalPaths are sockets of the local syslog daemon on Linux, macOS and BSD systems
+var syslogLocalPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+var errSyslogQueueFull = errors.New("syslog queue is full, message dropped")
+
+// SyslogFacility is the syslog facility of messages
+type SyslogFacility uint8
+
+const (
+	SyslogFacilityKern SyslogFacility = iota
+	SyslogFacilityUser
+	SyslogFacilityMail
+	SyslogFacilityDaemon
+	SyslogFacilityAuth
+	SyslogFacilitySyslog
+	SyslogFacilityLPR
+	SyslogFacilityNews
+	SyslogFacilityUUCP
+	SyslogFacilityCron
+	SyslogFacilityAuthPriv
+	SyslogFacilityFTP
+)
+
+const (
+	SyslogFacilityLocal0 SyslogFacility = iota + 16
+	SyslogFacilityLocal1
+	SyslogFacilityLocal2
+	SyslogFacilityLocal3
+	SyslogFacilityLocal4
+	SyslogFacilityLocal5
+	SyslogFacilityLocal6
+	SyslogFacilityLocal7
+)
+
+// SyslogOptions configures syslog messages and the connection to the syslog server
+type SyslogOptions struct {
+	// Facility of messages, the default is user
+	Facility SyslogFacility
+	// AppName is the application name of messages, the default is the program name
+	AppName string
+	// Hostname of messages, the default is the host name
+	Hostname string
+	// RFC3164 enables the legacy BSD syslog format instead of RFC 5424
+	RFC3164 bool
+	// TLSConfig is the configuration of "tls" connections
+	TLSConfig *tls.Config
+}
+
+// syslogSeverity maps level to syslog severity: levels above error map to critical, alert and emergency
+// in steps of 4, levels between info and warn map to notice
+func syslogSeverity(level Level) int {
+	switch {
+	case level >= LevelError+12:
+		return 0 // emergency
+	case level >= LevelError+8:
+		return 1 // alert
+	case level >= LevelError+4:
+		return 2 // critical
+	case level >= LevelError:
+		return 3 // error
+	case level >= LevelWarn:
+		return 4 // warning
+	case level > LevelInfo:
+		return 5 // notice
+	case level >= LevelInfo:
+		return 6 // informational
+	default:
+		return 7 // debug
+	}
+}
+
+// SyslogHandler writes records as RFC 5424 syslog messages, one message per Write call ended by a newline.
+// Attributes are written as parameters of structured data, attributes of groups are flattened with dots.
+// In the RFC 3164 format attributes are appended to the message as logfmt pairs. ReplaceAttr of opts is
+// applied to the source and the attributes before they are written.
+type SyslogHandler struct {
+	opts   HandlerOptions
+	syslog SyslogOptions
+	pid    string
+	attrs  []Attr
+	groups []string
+	group  string
+
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+// NewSyslogHandler creates syslog handler, use SyslogWriter to send messages to a syslog server
+func NewSyslogHandler(w io.Writer, opts *HandlerOptions, syslog SyslogOptions) *SyslogHandler {
+	h := &SyslogHandler{w: w, syslog: syslog, pid: strconv.Itoa(os.Getpid()), mu: &sync.Mutex{}}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.syslog.Facility == SyslogFacilityKern {
+		// kernel messages can't be sent by user processes
+		h.syslog.Facility = SyslogFacilityUser
+	}
+	if h.syslog.AppName == "" {
+		h.syslog.AppName = filepath.Base(os.Args[0])
+	}
+	if h.syslog.Hostname == "" {
+		h.syslog.Hostname, _ = os.Hostname()
+	}
+	return h
+}
+
+func (h *SyslogHandler) Enabled(_ context.Context, level Level) bool {
+	minLevel := LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+func (h *SyslogHandler) Handle(_ context.Context, r Record) error {
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	pri := "<" + strconv.Itoa(int(h.syslog.Facility)*8+syslogSeverity(r.Level)) + ">"
+
+	var buf bytes.Buffer
+	if h.syslog.RFC3164 {
+		h.append3164(&buf, pri, t, r)
+	} else {
+		h.append5424(&buf, pri, t, r)
+	}
+	buf.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf.Bytes())
+
+	return err
+}
+
+func (h *SyslogHandler) append5424(buf *bytes.Buffer, pri string, t time.Time, r Record) {
+	buf.WriteString(pri + "1 ")
+	buf.WriteString(t.Format("2006-01-02T15:04:05.000000Z07:00"))
+	buf.WriteByte(' ')
+	buf.WriteString(syslogHeaderField(h.syslog.Hostname, 255))
+	buf.WriteByte(' ')
+	buf.WriteString(syslogHeaderField(h.syslog.AppName, 48))
+	buf.WriteByte(' ')
+	buf.WriteString(h.pid)
+	buf.WriteString(" - ")
+
+	start := buf.Len()
+	buf.WriteString("[" + syslogSDID)
+	h.attrsFunc(r, func(key string, v Value) {
+		buf.WriteByte(' ')
+		buf.WriteString(syslogParamName(key))
+		buf.WriteString(`="`)
+		syslogParamValueReplacer.WriteString(buf, syslogValueString(v))
+		buf.WriteByte('"')
+	})
+	if buf.Len() == start+len(syslogSDID)+1 {
+		buf.Truncate(start)
+		buf.WriteByte('-')
+	} else {
+		buf.WriteByte(']')
+	}
+
+	if r.Message != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(r.Message)
+	}
+}
+
+func (h *SyslogHandler) append3164(buf *bytes.Buffer, pri string, t time.Time, r Record) {
+	buf.WriteString(pri)
+	buf.WriteString(t.Format(time.Stamp))
+	buf.WriteByte(' ')
+	buf.WriteString(syslogHeaderField(h.syslog.Hostname, 255))
+	buf.WriteByte(' ')
+	buf.WriteString(syslogHeaderField(h.syslog.AppName, 32))
+	buf.WriteString("[" + h.pid + "]: ")
+	// the message is ended by a newline in stream transports
+	buf.WriteString(strings.ReplaceAll(r.Message, "\n", " "))
+
+	line := buf.AvailableBuffer()
+	h.attrsFunc(r, func(key string, v Value) {
+		line = appendLogfmtKey(line, "", key)
+		line = appendLogfmtValue(line, v)
+	})
+	buf.Write(line)
+}
+
+// attrsFunc calls fn for the source, the handler and the record attributes with flattened keys
+func (h *SyslogHandler) attrsFunc(r Record, fn func(key string, v Value)) {
+	rep := h.opts.ReplaceAttr
+	param := func(key string, v Value) {
+		if src, ok := v.Any().(*Source); ok {
+			v = StringValue(src.File + ":" + strconv.Itoa(src.Line))
+		}
+		fn(key, v)
+	}
+	if h.opts.AddSource && r.PC != 0 {
+		syslogAttrFunc("", nil, Any(SourceKey, recordSource(r)), rep, param)
+	}
+	for _, attr := range h.attrs {
+		syslogAttrFunc("", nil, attr, rep, param)
+	}
+	r.Attrs(func(attr Attr) bool {
+		syslogAttrFunc(h.group, h.groups, attr, rep, param)
+		return true
+	})
+}
+
+// syslogAttrFunc applies rep to the attribute if it isn't nil and calls fn for it, members of groups are
+// flattened with dots
+func syslogAttrFunc(prefix string, groups []string, attr Attr, rep func([]string, Attr) Attr, fn func(key string, v Value)) {
+	attr.Value = attr.Value.Resolve()
+	if rep != nil && attr.Value.Kind() != KindGroup {
+		attr = rep(groups, attr)
+		attr.Value = attr.Value.Resolve()
+	}
+	if attr.Equal(Attr{}) {
+		return
+	}
+
+	if attr.Value.Kind() == KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+			groups = append(groups[:len(groups):len(groups)], attr.Key)
+		}
+		for _, member := range attr.Value.Group() {
+			syslogAttrFunc(prefix, groups, member, rep, fn)
+		}
+		return
+	}
+
+	fn(prefix+attr.Key, attr.Value)
+}
+
+func (h *SyslogHandler) WithAttrs(attrs []Attr) Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.attrs = append([]Attr(nil), h.attrs...)
+	for _, attr := range attrs {
+		for i := len(h.groups) - 1; i >= 0; i-- {
+			attr = Attr{Key: h.groups[i], Value: GroupValue(attr)}
+		}
+		h2.attrs = append(h2.attrs, attr)
+	}
+	return &h2
+}
+
+func (h *SyslogHandler) WithGroup(name string) Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	h2.group = h.group + name + "."
+	return &h2
+}
+
+var syslogParamValueReplacer = strings.NewReplacer(`"`, `\"`, `\`, `\\`, `]`, `\]`)
+
+// syslogHeaderField returns header field of printable ASCII characters, "-" for an empty value
+func syslogHeaderField(s string, maxLen int) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "-"
+	}
+	return s[:min(len(s), maxLen)]
+}
+
+// syslogParamName returns structured data parameter name of at most 32 printable ASCII characters
+// without '=', ']', '"' and spaces
+func syslogParamName(key string) string {
+	key = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+	if key == "" {
+		return "_"
+	}
+	return key[:min(len(key), 32)]
+}
+
+func syslogValueString(v Value) string {
+	switch v.Kind() {
+	case KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	case KindAny:
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+	}
+	return v.String()
+}
+
+// SyslogWriter sends syslog messages written by SyslogHandler to a syslog server. Write doesn't block,
+// messages are queued and sent in the background, the connection is reestablished on failure.
+// Messages are dropped when the queue is full.
+type SyslogWriter struct {
+	network   string
+	address   string
+	framing   func(buf *bytes.Buffer, msg []byte)
+	tlsConfig *tls.Config
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan syslogItem
+	stop   chan struct{}
+	done   chan struct{}
+
+	// conn, connFraming, connClosed, buf and unavailable are used by the sending goroutine only
+	conn        net.Conn
+	connFraming func(buf *bytes.Buffer, msg []byte)
+	connClosed  *atomic.Bool
+	buf         bytes.Buffer
+	unavailable bool
+	dropped     atomic.Uint64
+}
+
+type syslogItem struct {
+	msg     []byte
+	flushed chan struct{}
+}
+
+// NewSyslogWriter creates writer to the syslog server at address, network is "udp", "tcp", "tls", "unix"
+// or "unixgram", an empty network connects to the local syslog daemon. The connection is established
+// in the background.
+func NewSyslogWriter(network, address string, opts SyslogOptions) (*SyslogWriter, error) {
+	w := &SyslogWriter{
+		network:   network,
+		address:   address,
+		tlsConfig: opts.TLSConfig,
+		queue:     make(chan syslogItem, syslogQueueSize),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	switch network {
+	case "", "udp", "udp4", "udp6", "unixgram":
+		// one message per datagram
+	case "tcp", "tcp4", "tcp6", "tls", "unix":
+		// RFC 6587 framing of stream transports
+		w.framing = syslogOctetCounting
+		if opts.RFC3164 {
+			w.framing = syslogNonTransparent
+		}
+	default:
+		return nil, fmt.Errorf("unsupported syslog network '%s'", network)
+	}
+	if network == "" && address != "" {
+		return nil, errors.New("address of the local syslog daemon must be empty")
+	}
+
+	go w.run()
+
+	return w, nil
+}
+
+func syslogOctetCounting(buf *bytes.Buffer, msg []byte) {
+	buf.WriteString(strconv.Itoa(len(msg)))
+	buf.WriteByte(' ')
+	buf.Write(msg)
+}
+
+func syslogNonTransparent(buf *bytes.Buffer, msg []byte) {
+	buf.Write(msg)
+	buf.WriteByte('\n')
+}
+
+// Write queues one syslog message, the trailing newline is removed
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	msg := bytes.Clone(bytes.TrimSuffix(p, []byte("\n")))
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	select {
+	case w.queue <- syslogItem{msg: msg}:
+		return len(p), nil
+	default:
+		w.dropped.Add(1)
+		return 0, errSyslogQueueFull
+	}
+}
+
+// Dropped returns the number of messages dropped because the queue was full or the server was unavailable
+// when the writer was closed
+func (w *SyslogWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Flush waits until the queued messages are sent
+func (w *SyslogWriter) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+
+	w.mu.RLock()
+	if w.closed {
+		w.mu.RUnlock()
+		return nil
+	}
+	select {
+	case w.queue <- syslogItem{flushed: flushed}:
+		w.mu.RUnlock()
+	case <-ctx.Done():
+		w.mu.RUnlock()
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close sends the queued messages and closes the connection, messages are dropped
+// if the server is unavailable
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.stop)
+	close(w.queue)
+	w.mu.Unlock()
+
+	<-w.done
+
+	if w.conn == nil {
+		return nil
+	}
+	return w.conn.Close()
+}
+
+func (w *SyslogWriter) run() {
+	defer close(w.done)
+
+	for item := range w.queue {
+		if item.flushed != nil {
+			close(item.flushed)
+			continue
+		}
+		w.send(item.msg)
+	}
+}
+
+// send writes the message reconnecting with backoff until it is written or the writer is closed
+func (w *SyslogWriter) send(msg []byte) {
+	if w.unavailable {
+		w.dropped.Add(1)
+		return
+	}
+
+	backoff := syslogMinBackoff
+	for {
+		if err := w.write(msg); err == nil {
+			return
+		}
+
+		select {
+		case <-w.stop:
+			// don't wait for the server on close, the remaining messages are dropped
+			w.unavailable = true
+			w.dropped.Add(1)
+			return
+		default:
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-w.stop:
+			timer.Stop()
+		}
+		backoff = min(2*backoff, syslogMaxBackoff)
+	}
+}
+
+func (w *SyslogWriter) write(msg []byte) error {
+	if w.conn != nil && w.connClosed != nil && w.connClosed.Load() {
+		// a write to the connection closed by the server may succeed, but the message is lost
+		w.conn.Close()
+		w.conn = nil
+	}
+	if w.conn == nil {
+		conn, err := w.dial()
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+		w.connFraming = w.framing
+		if w.network == "" && conn.RemoteAddr().Network() == "unix" {
+			// local daemons listening on a stream socket split messages at newlines
+			w.connFraming = syslogNonTransparent
+		}
+		if w.connFraming != nil {
+			w.connClosed = watchSyslogConn(conn)
+		}
+	}
+
+	w.buf.Reset()
+	if w.connFraming != nil {
+		w.connFraming(&w.buf, msg)
+		w.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout))
+	} else {
+		w.buf.Write(msg)
+	}
+	if _, err := w.conn.Write(w.buf.Bytes()); err != nil {
+		w.conn.Close()
+		w.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (w *SyslogWriter) dial() (net.Conn, error) {
+	switch w.network {
+	case "":
+		for _, network := range []string{"unixgram", "unix"} {
+			for _, path := range syslogLocalPaths {
+				if conn, err := net.DialTimeout(network, path, syslogDialTimeout); err == nil {
+					return conn, nil
+				}
+			}
+		}
+		return nil, errors.New("local syslog daemon is unavailable")
+	case "tls":
+		dialer := &net.Dialer{Timeout: syslogDialTimeout}
+		return tls.DialWithDialer(dialer, "tcp", w.address, w.tlsConfig)
+	default:
+		return net.DialTimeout(w.network, w.address, syslogDialTimeout)
+	}
+}
+
+// watchSyslogConn reads the stream connection until it is closed, syslog servers don't send data,
+// so the connection closed by the server is detected before the next write
+func watchSyslogConn(conn net.Conn) *atomic.Bool {
+	closed := &atomic.Bool{}
+	go func() {
+		io.Copy(io.Discard, conn)
+		closed.Store(true)
+	}()
+	return closed
+}
@@ -0,0 +1,332 @@
+package glog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogSeverity(t *testing.T) {
+	testCases := []struct {
+		level    Level
+		severity int
+	}{
+		{LevelDebug - 4, 7},
+		{LevelDebug, 7},
+		{LevelInfo, 6},
+		{LevelInfo + 2, 5},
+		{LevelWarn, 4},
+		{LevelError, 3},
+		{LevelError + 4, 2},
+		{LevelError + 8, 1},
+		{LevelError + 12, 0},
+		{LevelError + 100, 0},
+	}
+	for _, testCase := range testCases {
+		if s := syslogSeverity(testCase.level); s != testCase.severity {
+			t.Errorf("expected severity %d for %s, got %d", testCase.severity, testCase.level, s)
+		}
+	}
+}
+
+func TestSyslogHandler5424(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewSyslogHandler(&buf, &HandlerOptions{Level: LevelDebug}, SyslogOptions{
+		Facility: SyslogFacilityLocal0,
+		AppName:  "my app",
+		Hostname: "host1",
+	})
+	logger := New(h).With(StringAttr(NameKey, "worker")).WithGroup("job")
+
+	logger.Warn("job failed", IntAttr("id", 7), StringAttr("note", `a "quoted" \ value]`), ErrAttr(errors.New("timeout")))
+
+	expected := regexp.MustCompile(`^<132>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}(Z|[+-]\d\d:\d\d) host1 my_app ` +
+		strconv.Itoa(os.Getpid()) + ` - \[glog@32473 name="worker" job\.id="7" job\.note="a \\"quoted\\" \\\\ value\\]" job\.error="timeout"\] job failed\n$`)
+	if !expected.Match(buf.Bytes()) {
+		t.Errorf("unexpected message %q", buf.String())
+	}
+	buf.Reset()
+
+	New(h).Debug("")
+	if !strings.HasSuffix(buf.String(), " - -\n") || !strings.HasPrefix(buf.String(), "<135>1 ") {
+		t.Errorf("unexpected message without structured data %q", buf.String())
+	}
+	buf.Reset()
+
+	// ReplaceAttr is applied to the parameters
+	replace := func(groups []string, a Attr) Attr {
+		if a.Key == "password" {
+			return Attr{}
+		}
+		if len(groups) == 1 && groups[0] == "job" && a.Key == "id" {
+			return StringAttr("job_id", a.Value.String())
+		}
+		return a
+	}
+	New(NewSyslogHandler(&buf, &HandlerOptions{ReplaceAttr: replace}, SyslogOptions{Hostname: "host1", AppName: "app"})).
+		With(StringAttr("password", "secret")).WithGroup("job").Info("replaced", IntAttr("id", 7), StringAttr("password", "secret"))
+	if !strings.Contains(buf.String(), `[glog@32473 job.job_id="7"] replaced`) {
+		t.Errorf("unexpected message with replaced attributes %q", buf.String())
+	}
+	buf.Reset()
+
+	New(NewSyslogHandler(&buf, nil, SyslogOptions{})).Info("default facility")
+	if !strings.HasPrefix(buf.String(), "<14>1 ") || !strings.Contains(buf.String(), " "+filepath.Base(os.Args[0])+" ") {
+		t.Errorf("unexpected message with default options %q", buf.String())
+	}
+}
+
+func TestSyslogHandler3164(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(NewSyslogHandler(&buf, nil, SyslogOptions{Hostname: "host1", AppName: "app", RFC3164: true}))
+
+	logger.Error("disk\nfull", StringAttr("path", "/var/log"), Group("usage", IntAttr("percent", 100)))
+
+	expected := regexp.MustCompile(`^<11>[A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d host1 app\[` + strconv.Itoa(os.Getpid()) +
+		`\]: disk full path=/var/log usage\.percent=100\n$`)
+	if !expected.Match(buf.Bytes()) {
+		t.Errorf("unexpected message %q", buf.String())
+	}
+}
+
+func TestSyslogWriterUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %s", err.Error())
+	}
+	defer conn.Close()
+
+	logger, handle, err := Build(
+		WithOutputFormat(OutputFormatSyslog),
+		WithOutputFilePath("udp://"+conn.LocalAddr().String()),
+		WithSyslogFacility(SyslogFacilityDaemon),
+		WithSyslogAppName("test"),
+		WithAddSource(false),
+		WithSetDefault(false),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	defer handle.Close()
+
+	logger.Info("started", StringAttr("version", "1.0"))
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	packet := make([]byte, 2048)
+	n, _, err := conn.ReadFrom(packet)
+	if err != nil {
+		t.Fatalf("read error: %s", err.Error())
+	}
+	msg := string(packet[:n])
+	if !strings.HasPrefix(msg, "<30>1 ") || !strings.HasSuffix(msg, ` test `+strconv.Itoa(os.Getpid())+` - [glog@32473 version="1.0"] started`) {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
+
+func TestSyslogWriterUnixgram(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	conn, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Skipf("unixgram is not supported: %s", err.Error())
+	}
+	defer conn.Close()
+
+	w, err := NewSyslogWriter("unixgram", path, SyslogOptions{})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	defer w.Close()
+
+	New(NewSyslogHandler(w, nil, SyslogOptions{RFC3164: true})).Info("local")
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	packet := make([]byte, 2048)
+	n, _, err := conn.ReadFrom(packet)
+	if err != nil {
+		t.Fatalf("read error: %s", err.Error())
+	}
+	if msg := string(packet[:n]); !strings.HasPrefix(msg, "<14>") || !strings.HasSuffix(msg, "]: local") {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
+
+func TestSyslogWriterLocalStream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets are not supported: %s", err.Error())
+	}
+	defer ln.Close()
+
+	paths := syslogLocalPaths
+	syslogLocalPaths = []string{path}
+	defer func() { syslogLocalPaths = paths }()
+
+	w, err := NewSyslogWriter("", "", SyslogOptions{})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	defer w.Close()
+	logger := New(NewSyslogHandler(w, nil, SyslogOptions{RFC3164: true}))
+	logger.Info("first")
+	logger.Info("second")
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("accept error: %s", err.Error())
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	// messages on the stream socket are separated by newlines
+	r := bufio.NewReader(conn)
+	for _, expected := range []string{"first", "second"} {
+		msg, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read error: %s", err.Error())
+		}
+		if !strings.HasPrefix(msg, "<14>") || !strings.HasSuffix(msg, "]: "+expected+"\n") {
+			t.Errorf("unexpected message %q", msg)
+		}
+	}
+}
+
+// readOctetCounted reads RFC 6587 octet counted messages
+func readOctetCounted(r *bufio.Reader) (string, error) {
+	length, err := r.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+	if err != nil {
+		return "", err
+	}
+	msg := make([]byte, n)
+	_, err = io.ReadFull(r, msg)
+	return string(msg), err
+}
+
+func TestSyslogWriterTCPReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %s", err.Error())
+	}
+	defer ln.Close()
+
+	messages := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			// each connection receives one message and is closed by the server
+			msg, err := readOctetCounted(bufio.NewReader(conn))
+			conn.Close()
+			if err == nil {
+				messages <- msg
+			}
+		}
+	}()
+
+	w, err := NewSyslogWriter("tcp", ln.Addr().String(), SyslogOptions{})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	defer w.Close()
+	logger := New(NewSyslogHandler(w, nil, SyslogOptions{}))
+
+	for _, expected := range []string{"first", "second", "third"} {
+		// wait for the writer to notice the closed connection
+		time.Sleep(50 * time.Millisecond)
+		logger.Info(expected)
+		select {
+		case msg := <-messages:
+			if !strings.HasSuffix(msg, " - - "+expected) {
+				t.Errorf("unexpected message %q", msg)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timeout waiting for message %s", expected)
+		}
+	}
+}
+
+func TestSyslogWriterUnavailable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %s", err.Error())
+	}
+	address := ln.Addr().String()
+	ln.Close()
+
+	w, err := NewSyslogWriter("tcp", address, SyslogOptions{})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+
+	start := time.Now()
+	var dropped error
+	for i := 0; i < syslogQueueSize+10; i++ {
+		if _, err := w.Write([]byte("<14>1 - - - - - message\n")); err != nil {
+			dropped = err
+		}
+	}
+	if time.Since(start) > time.Second {
+		t.Error("expected non-blocking writes")
+	}
+	if !errors.Is(dropped, errSyslogQueueFull) || w.Dropped() == 0 {
+		t.Errorf("expected dropped messages, got %v", dropped)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := w.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+	if w.Dropped() < syslogQueueSize {
+		t.Errorf("expected queued messages dropped on close, got %d", w.Dropped())
+	}
+	if _, err := w.Write([]byte("message")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("expected os.ErrClosed, got %v", err)
+	}
+}
+
+func TestSyslogDestinations(t *testing.T) {
+	if _, err := NewSyslogWriter("http", "localhost:514", SyslogOptions{}); err == nil {
+		t.Error("expected error for unsupported network")
+	}
+
+	path := filepath.Join(t.TempDir(), "syslog.log")
+	logger, handle, err := Build(WithOutputFormat(OutputFormatSyslog3164), WithOutputFilePath(path), WithAddSource(false), WithSetDefault(false))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	logger.Info("to file")
+	handle.Close()
+
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), "<14>") || !strings.HasSuffix(string(data), "]: to file\n") {
+		t.Errorf("unexpected file content %q", data)
+	}
+
+	for _, format := range []string{"syslog", "syslog3164"} {
+		f, err := ParseOutputFormat(format)
+		if err != nil || f.String() != format {
+			t.Errorf("unexpected format %s for %s, err %v", f, format, err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package glog
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	TraceIDKey    = "trace_id"
+	SpanIDKey     = "span_id"
+	TraceFlagsKey = "trace_flags"
+
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+
+	// maxTraceStateLength is the length of tracestate the W3C Trace Context requires to propagate
+	maxTraceStateLength = 512
+)
+
+var errInvalidTraceParent = errors.New("invalid traceparent")
+
+// TraceID is the identifier of a distributed trace
+type TraceID [16]byte
+
+// SpanID is the identifier of a span within a trace
+type SpanID [8]byte
+
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// TraceContext identifies the span a record is logged in
+type TraceContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	// Flags are the trace flags, 1 means the trace is sampled
+	Flags byte
+	// State is the vendor specific tracestate propagated with the trace
+	State string
+}
+
+// IsValid reports whether both trace and span identifiers are set
+func (tc TraceContext) IsValid() bool {
+	return tc.TraceID.IsValid() && tc.SpanID.IsValid()
+}
+
+type contextTraceKey struct{}
+
+// ContextWithTraceContext puts trace context to context
+func ContextWithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, contextTraceKey{}, tc)
+}
+
+// TraceContextFromContext returns trace context from context
+func TraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	if ctx == nil {
+		return TraceContext{}, false
+	}
+	tc, ok := ctx.Value(contextTraceKey{}).(TraceContext)
+	return tc, ok && tc.IsValid()
+}
+
+// ParseTraceParent parses the W3C Trace Context traceparent header value, e.g.
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func ParseTraceParent(s string) (TraceContext, error) {
+	var tc TraceContext
+	// version 00 has exactly four fields, later versions can append fields
+	if len(s) < 55 || len(s) > 55 && (s[:2] == "00" || s[55] != '-') {
+		return tc, errInvalidTraceParent
+	}
+	if s[2] != '-' || s[35] != '-' || s[52] != '-' || s[:2] == "ff" {
+		return tc, errInvalidTraceParent
+	}
+
+	var version, flags [1]byte
+	if !decodeLowerHex(version[:], s[:2]) || !decodeLowerHex(tc.TraceID[:], s[3:35]) ||
+		!decodeLowerHex(tc.SpanID[:], s[36:52]) || !decodeLowerHex(flags[:], s[53:55]) {
+		return TraceContext{}, errInvalidTraceParent
+	}
+	tc.Flags = flags[0]
+	if !tc.IsValid() {
+		return TraceContext{}, errInvalidTraceParent
+	}
+	return tc, nil
+}
+
+// TraceParent returns the traceparent header value of the trace context
+func (tc TraceContext) TraceParent() string {
+	return "00-" + tc.TraceID.String() + "-" + tc.SpanID.String() + "-" + hex.EncodeToString([]byte{tc.Flags})
+}
+
+// TraceContextFromRequest returns trace context of the traceparent and tracestate headers of the request
+func TraceContextFromRequest(r *http.Request) (TraceContext, bool) {
+	tc, err := ParseTraceParent(strings.TrimSpace(r.Header.Get(TraceParentHeader)))
+	if err != nil {
+		return TraceContext{}, false
+	}
+
+	state := strings.TrimSpace(strings.Join(r.Header.Values(TraceStateHeader), ","))
+	if len(state) <= maxTraceStateLength {
+		tc.State = state
+	}
+	return tc, true
+}
+
+// InjectTraceContext sets the traceparent and tracestate headers of the outbound request from the trace
+// context of its context
+func InjectTraceContext(r *http.Request) {
+	tc, ok := TraceContextFromContext(r.Context())
+	if !ok {
+		return
+	}
+
+	r.Header.Set(TraceParentHeader, tc.TraceParent())
+	if tc.State != "" {
+		r.Header.Set(TraceStateHeader, tc.State)
+	} else {
+		r.Header.Del(TraceStateHeader)
+	}
+}
+
+// TraceTransport injects the trace context of request contexts into outbound requests
+type TraceTransport struct {
+	// Base is the transport sending requests, http.DefaultTransport if nil
+	Base http.RoundTripper
+}
+
+func (t *TraceTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if _, ok := TraceContextFromContext(r.Context()); !ok {
+		return base.RoundTrip(r)
+	}
+
+	// round trippers must not modify the request
+	r = r.Clone(r.Context())
+	InjectTraceContext(r)
+	return base.RoundTrip(r)
+}
+
+// decodeLowerHex decodes lowercase hex string of the length of dst
+func decodeLowerHex(dst []byte, s string) bool {
+	if len(s) != 2*len(dst) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// TraceHandler adds trace_id, span_id and trace_flags attributes of the trace context of the context to
+// records, see ContextWithTraceContext. The attributes are added at the top level, also to records logged
+// in groups.
+//
+// Deprecated: use NewContextHandler with ContextHandlerOptions.TraceAttrs, which also adds attributes
+// of ContextWithAttrs.
+type TraceHandler struct {
+	*ContextHandler
+}
+
+// Deprecated: use NewContextHandler with ContextHandlerOptions.TraceAttrs.
+func NewTraceHandler(next Handler) *TraceHandler {
+	return &TraceHandler{&ContextHandler{next: next, opts: ContextHandlerOptions{TraceAttrs: true}, traceOnly: true}}
+}
+
+// traceAttrs returns trace_id, span_id and trace_flags attributes of the trace context
+func traceAttrs(tc TraceContext) []Attr {
+	return []Attr{
+		StringAttr(TraceIDKey, tc.TraceID.String()),
+		StringAttr(SpanIDKey, tc.SpanID.String()),
+		StringAttr(TraceFlagsKey, hex.EncodeToString([]byte{tc.Flags})),
+	}
+}
@@ -0,0 +1,180 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceParent(t *testing.T) {
+	tc, err := ParseTraceParent(testTraceParent)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if tc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || tc.SpanID.String() != "00f067aa0ba902b7" || tc.Flags != 1 {
+		t.Errorf("unexpected trace context %+v", tc)
+	}
+	if tc.TraceParent() != testTraceParent {
+		t.Errorf("expected %s, got %s", testTraceParent, tc.TraceParent())
+	}
+
+	// later versions can append fields
+	if _, err := ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); err != nil {
+		t.Errorf("expected future version to be parsed, got %s", err.Error())
+	}
+
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0g",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}
+	for _, s := range invalid {
+		if _, err := ParseTraceParent(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
+
+func TestTraceContextPropagation(t *testing.T) {
+	in := httptest.NewRequest("GET", "http://testing", nil)
+	in.Header.Set(TraceParentHeader, testTraceParent)
+	in.Header.Add(TraceStateHeader, "congo=t61rcWkgMzE")
+	in.Header.Add(TraceStateHeader, "rojo=00f067aa0ba902b7")
+
+	tc, ok := TraceContextFromRequest(in)
+	if !ok || tc.State != "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7" {
+		t.Fatalf("unexpected trace context %+v", tc)
+	}
+
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &TraceTransport{}}
+	out, _ := http.NewRequestWithContext(ContextWithTraceContext(context.Background(), tc), "GET", server.URL, nil)
+	resp, err := client.Do(out)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	resp.Body.Close()
+
+	if received.Get(TraceParentHeader) != testTraceParent || received.Get(TraceStateHeader) != tc.State {
+		t.Errorf("unexpected propagated headers %v", received)
+	}
+	if out.Header.Get(TraceParentHeader) != "" {
+		t.Errorf("expected original request not to be modified")
+	}
+
+	// requests without trace context are sent as is
+	out, _ = http.NewRequest("GET", server.URL, nil)
+	resp, err = client.Do(out)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	resp.Body.Close()
+	if received.Get(TraceParentHeader) != "" {
+		t.Errorf("unexpected traceparent %s", received.Get(TraceParentHeader))
+	}
+}
+
+func TestTraceHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(NewTraceHandler(NewJSONHandler(&buf, nil)))
+	tc, _ := ParseTraceParent(testTraceParent)
+	ctx := ContextWithTraceContext(ContextWithAttrs(context.Background(), StringAttr("tenant", "acme")), tc)
+
+	logger.With(StringAttr("service", "api")).WithGroup("request").With(StringAttr("method", "GET")).InfoContext(ctx, "handled", IntAttr("status", 200))
+	m := decodeJSONLine(t, &buf)
+	request, _ := m["request"].(map[string]any)
+	if m[TraceIDKey] != "4bf92f3577b34da6a3ce929d0e0e4736" || m[SpanIDKey] != "00f067aa0ba902b7" || m[TraceFlagsKey] != "01" ||
+		m["service"] != "api" || request["method"] != "GET" || request["status"] != float64(200) || request["tenant"] != nil {
+		t.Errorf("unexpected record %v", m)
+	}
+
+	// empty groups are omitted
+	logger.WithGroup("empty").InfoContext(ctx, "handled")
+	if m := decodeJSONLine(t, &buf); m["empty"] != nil || m[TraceIDKey] == nil {
+		t.Errorf("unexpected record %v", m)
+	}
+
+	logger.WithGroup("request").Info("handled", IntAttr("status", 200))
+	if m := decodeJSONLine(t, &buf); m[TraceIDKey] != nil || m["request"] == nil {
+		t.Errorf("unexpected record without trace context %v", m)
+	}
+}
+
+func TestContextHandlerTraceAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(NewContextHandler(NewJSONHandler(&buf, nil), ContextHandlerOptions{TraceAttrs: true}))
+	tc, _ := ParseTraceParent(testTraceParent)
+	ctx := ContextWithTraceContext(ContextWithAttrs(context.Background(), StringAttr("tenant", "acme")), tc)
+
+	logger.With(StringAttr("service", "api")).InfoContext(ctx, "handled", IntAttr("status", 200))
+	m := decodeJSONLine(t, &buf)
+	if m[TraceIDKey] != "4bf92f3577b34da6a3ce929d0e0e4736" || m[SpanIDKey] != "00f067aa0ba902b7" || m[TraceFlagsKey] != "01" ||
+		m["service"] != "api" || m["tenant"] != "acme" || m["status"] != float64(200) {
+		t.Errorf("unexpected record %v", m)
+	}
+
+	// trace attributes are logged at the top level, context attributes in the innermost group
+	logger.With(StringAttr("service", "api")).WithGroup("request").With(StringAttr("method", "GET")).InfoContext(ctx, "handled", IntAttr("status", 200))
+	m = decodeJSONLine(t, &buf)
+	request, _ := m["request"].(map[string]any)
+	if m[TraceIDKey] != "4bf92f3577b34da6a3ce929d0e0e4736" || m["service"] != "api" ||
+		request["method"] != "GET" || request["status"] != float64(200) || request["tenant"] != "acme" || request[TraceIDKey] != nil {
+		t.Errorf("unexpected grouped record %v", m)
+	}
+
+	// empty groups are omitted
+	New(NewContextHandler(NewJSONHandler(&buf, nil), ContextHandlerOptions{TraceAttrs: true})).WithGroup("empty").InfoContext(
+		ContextWithTraceContext(context.Background(), tc), "handled")
+	if m := decodeJSONLine(t, &buf); m["empty"] != nil || m[TraceIDKey] == nil {
+		t.Errorf("unexpected record %v", m)
+	}
+
+	logger.WithGroup("request").Info("handled", IntAttr("status", 200))
+	if m := decodeJSONLine(t, &buf); m[TraceIDKey] != nil || m["request"] == nil {
+		t.Errorf("unexpected record without trace context %v", m)
+	}
+
+	// trace attributes are optional
+	New(NewContextHandler(NewJSONHandler(&buf, nil), ContextHandlerOptions{})).InfoContext(ctx, "handled")
+	if m := decodeJSONLine(t, &buf); m[TraceIDKey] != nil || m["tenant"] != "acme" {
+		t.Errorf("unexpected record without trace attributes %v", m)
+	}
+}
+
+func TestHttpAccessLogMiddlewareTraceContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, handle, err := Build(WithOutputFilePath(path), WithTraceAttrs(true), WithSetDefault(false))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+
+	httpHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		L(r.Context()).InfoContext(r.Context(), "Handled")
+	})
+	req := httptest.NewRequest("GET", "http://testing", nil).WithContext(ContextWithLogger(context.Background(), logger))
+	req.Header.Set(TraceParentHeader, testTraceParent)
+	NewHttpAccessLogMiddleware("access")(httpHandler).ServeHTTP(httptest.NewRecorder(), req)
+	handle.Close()
+
+	data, _ := os.ReadFile(path)
+	if strings.Count(string(data), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7","trace_flags":"01"`) != 2 {
+		t.Errorf("unexpected file content %s", data)
+	}
+}
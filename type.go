@@ -15,6 +15,20 @@ func (of OutputFormat) String() string {
 		return "json"
 	case OutputFormatTEXT:
 		return "text"
+	case OutputFormatLogfmt:
+		return "logfmt"
+	case OutputFormatECS:
+		return "ecs"
+	case OutputFormatGELF:
+		return "gelf"
+	case OutputFormatSyslog:
+		return "syslog"
+	case OutputFormatSyslog3164:
+		return "syslog3164"
+	case OutputFormatJournal:
+		return "journal"
+	case OutputFormatOTLP:
+		return "otlp"
 	default:
 		return "json"
 	}
@@ -26,14 +40,53 @@ func (of OutputFormat) toDigit(v string) (OutputFormat, error) {
 		return OutputFormatJSON, nil
 	case "text":
 		return OutputFormatTEXT, nil
+	case "logfmt":
+		return OutputFormatLogfmt, nil
+	case "ecs":
+		return OutputFormatECS, nil
+	case "gelf":
+		return OutputFormatGELF, nil
+	case "syslog":
+		return OutputFormatSyslog, nil
+	case "syslog3164":
+		return OutputFormatSyslog3164, nil
+	case "journal":
+		return OutputFormatJournal, nil
+	case "otlp":
+		return OutputFormatOTLP, nil
 	default:
 		return 0, fmt.Errorf("unknown output format '%s'", v)
 	}
 }
 
+func (of OutputFormat) valid() bool {
+	switch of {
+	case OutputFormatJSON, OutputFormatTEXT, OutputFormatLogfmt, OutputFormatECS, OutputFormatGELF,
+		OutputFormatSyslog, OutputFormatSyslog3164, OutputFormatJournal, OutputFormatOTLP:
+		return true
+	default:
+		return false
+	}
+}
+
+func (of OutputFormat) isSyslog() bool {
+	return of == OutputFormatSyslog || of == OutputFormatSyslog3164
+}
+
 const (
 	OutputFormatJSON OutputFormat = iota
 	OutputFormatTEXT
+	OutputFormatLogfmt
+	OutputFormatECS
+	OutputFormatGELF
+	// OutputFormatSyslog is the RFC 5424 syslog format
+	OutputFormatSyslog
+	// OutputFormatSyslog3164 is the legacy BSD syslog format described by RFC 3164
+	OutputFormatSyslog3164
+	// OutputFormatJournal is the native protocol of the systemd journal
+	OutputFormatJournal
+	// OutputFormatOTLP is the OpenTelemetry log data model in the OTLP/JSON encoding
+	OutputFormatOTLP
 )
 
 func ParseOutputFormat(format string) (OutputFormat, error) {
@@ -41,6 +94,24 @@ func ParseOutputFormat(format string) (OutputFormat, error) {
 	return f.toDigit(strings.ToLower(format))
 }
 
+// MarshalText implements encoding.TextMarshaler
+func (of OutputFormat) MarshalText() ([]byte, error) {
+	if !of.valid() {
+		return nil, fmt.Errorf("unknown output format %d", of)
+	}
+	return []byte(of.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (of *OutputFormat) UnmarshalText(data []byte) error {
+	f, err := ParseOutputFormat(string(data))
+	if err != nil {
+		return err
+	}
+	*of = f
+	return nil
+}
+
 const (
 	LevelDebug = slog.LevelDebug
 	LevelInfo  = slog.LevelInfo
@@ -82,6 +153,7 @@ var (
 	NewTextHandler = slog.NewTextHandler
 	NewJSONHandler = slog.NewJSONHandler
 	New            = slog.New
+	NewRecord      = slog.NewRecord
 	SetDefault     = slog.SetDefault
 	GetDefault     = slog.Default
 
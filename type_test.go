@@ -47,6 +47,14 @@ func TestOutputFormat(t *testing.T) {
 		t.Errorf("expected OutputFormatTEXT, but got %s", v.String())
 	}
 
+	v, err = ParseOutputFormat("logfmt")
+	if err != nil {
+		t.Errorf("expected no error, but got %s", err.Error())
+	}
+	if v != OutputFormatLogfmt {
+		t.Errorf("expected OutputFormatLogfmt, but got %s", v.String())
+	}
+
 	v, err = ParseOutputFormat("abc")
 	if err == nil {
 		t.Error("expected error, but got no error")